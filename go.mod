@@ -7,6 +7,7 @@ require (
 	github.com/mmcdole/gofeed v1.3.0
 	github.com/shirou/gopsutil/v4 v4.25.1
 	github.com/tidwall/gjson v1.18.0
+	golang.org/x/crypto v0.33.0
 	golang.org/x/text v0.22.0
 	gopkg.in/yaml.v3 v3.0.1
 )
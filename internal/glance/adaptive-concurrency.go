@@ -0,0 +1,85 @@
+package glance
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	minAdaptiveConcurrency     = 2
+	initialAdaptiveConcurrency = 4
+	maxAdaptiveConcurrency     = 30
+	// backs off when more than this share of the requests in a batch fail
+	adaptiveConcurrencyMaxErrorRatio = 0.1
+	// backs off when requests take longer than this on average
+	adaptiveConcurrencySlowLatency = 3 * time.Second
+)
+
+// Picks how many requests get made at once based on how the upstream handled
+// the previous batch, starting low and adding a worker after every healthy
+// batch, halving them when too many requests fail and removing one when the
+// requests get slow
+type adaptiveConcurrencyLimit struct {
+	mu       sync.Mutex
+	limit    int
+	requests int
+	failed   int
+	latency  time.Duration
+}
+
+func newAdaptiveConcurrencyLimit() *adaptiveConcurrencyLimit {
+	return &adaptiveConcurrencyLimit{limit: initialAdaptiveConcurrency}
+}
+
+func (c *adaptiveConcurrencyLimit) workers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.limit
+}
+
+func (c *adaptiveConcurrencyLimit) record(latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests++
+	c.latency += latency
+
+	// an unchanged feed is as healthy a response as any
+	if err != nil && !errors.Is(err, errNotModified) {
+		c.failed++
+	}
+}
+
+// Adjusts the limit based on the requests recorded since the last adjustment
+func (c *adaptiveConcurrencyLimit) adjust() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.requests == 0 {
+		return
+	}
+
+	switch {
+	case float64(c.failed)/float64(c.requests) > adaptiveConcurrencyMaxErrorRatio:
+		c.limit = max(minAdaptiveConcurrency, c.limit/2)
+	case c.latency/time.Duration(c.requests) > adaptiveConcurrencySlowLatency:
+		c.limit = max(minAdaptiveConcurrency, c.limit-1)
+	default:
+		c.limit = min(maxAdaptiveConcurrency, c.limit+1)
+	}
+
+	c.requests, c.failed, c.latency = 0, 0, 0
+}
+
+func withAdaptiveConcurrencyRecording[O any](c *adaptiveConcurrencyLimit, task func(*http.Request) (O, error)) func(*http.Request) (O, error) {
+	return func(request *http.Request) (O, error) {
+		startedAt := time.Now()
+		output, err := task(request)
+		c.record(time.Since(startedAt), err)
+
+		return output, err
+	}
+}
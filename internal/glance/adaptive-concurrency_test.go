@@ -0,0 +1,97 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveConcurrencyLimit(t *testing.T) {
+	limit := newAdaptiveConcurrencyLimit()
+
+	batch := func(requests int, failed int, latency time.Duration) int {
+		for i := range requests {
+			var err error
+			if i < failed {
+				err = errors.New("timed out")
+			}
+
+			limit.record(latency, err)
+		}
+
+		limit.adjust()
+		return limit.workers()
+	}
+
+	if got := batch(10, 0, 100*time.Millisecond); got != initialAdaptiveConcurrency+1 {
+		t.Errorf("expected a healthy batch to add a worker, got %d workers", got)
+	}
+
+	if got := batch(10, 0, 100*time.Millisecond); got != initialAdaptiveConcurrency+2 {
+		t.Errorf("expected another healthy batch to add a worker, got %d workers", got)
+	}
+
+	if got := batch(10, 5, 100*time.Millisecond); got != (initialAdaptiveConcurrency+2)/2 {
+		t.Errorf("expected a failing batch to halve the workers, got %d workers", got)
+	}
+
+	if got := batch(10, 5, 100*time.Millisecond); got != minAdaptiveConcurrency {
+		t.Errorf("expected the workers to not go below %d, got %d", minAdaptiveConcurrency, got)
+	}
+
+	batch(10, 0, 100*time.Millisecond)
+	if got := batch(10, 0, 5*time.Second); got != minAdaptiveConcurrency {
+		t.Errorf("expected a slow batch to remove a worker, got %d workers", got)
+	}
+
+	// unchanged feeds count as healthy
+	if got := batch(10, 0, 0); got != minAdaptiveConcurrency+1 {
+		t.Fatalf("expected a healthy batch to add a worker, got %d workers", got)
+	}
+
+	for range 10 {
+		limit.record(0, errNotModified)
+	}
+	limit.adjust()
+
+	if got := limit.workers(); got != minAdaptiveConcurrency+2 {
+		t.Errorf("expected unchanged feeds to add a worker, got %d workers", got)
+	}
+}
+
+func TestBilibiliVideosAdaptiveConcurrency(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("BV"+r.URL.Path[1:], "Video", time.Now()))))
+	}))
+	defer server.Close()
+
+	config := "adaptive-concurrency: true\nrsshuburls:\n"
+	for i := range 5 {
+		config += fmt.Sprintf("  - %s/%d\n", server.URL, i+1)
+	}
+
+	widget := newTestBilibiliVideosWidget(t, config)
+
+	widget.update(context.Background())
+	widget.update(context.Background())
+	if got := widget.concurrencyLimit.workers(); got != initialAdaptiveConcurrency+2 {
+		t.Errorf("expected the workers to increase while all feeds succeed, got %d", got)
+	}
+
+	failing.Store(true)
+	widget.update(context.Background())
+	if got := widget.concurrencyLimit.workers(); got != (initialAdaptiveConcurrency+2)/2 {
+		t.Errorf("expected the workers to decrease once the feeds fail, got %d", got)
+	}
+}
@@ -0,0 +1,159 @@
+package glance
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type atomFeedEntry struct {
+	Title     string
+	URL       string
+	Author    string
+	Published time.Time
+}
+
+// Implemented by widgets whose items can be re-exposed as an Atom feed, the
+// entries should already have the widget's filters and limits applied
+type atomFeedWidget interface {
+	atomFeed() (string, []atomFeedEntry)
+}
+
+type atomFeedXml struct {
+	XMLName xml.Name       `xml:"feed"`
+	Xmlns   string         `xml:"xmlns,attr"`
+	ID      string         `xml:"id"`
+	Title   string         `xml:"title"`
+	Updated string         `xml:"updated"`
+	Link    atomLinkXml    `xml:"link"`
+	Author  atomAuthorXml  `xml:"author"`
+	Entries []atomEntryXml `xml:"entry"`
+}
+
+type atomLinkXml struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthorXml struct {
+	Name string `xml:"name"`
+}
+
+type atomEntryXml struct {
+	ID        string         `xml:"id"`
+	Title     string         `xml:"title"`
+	Link      atomLinkXml    `xml:"link"`
+	Author    *atomAuthorXml `xml:"author,omitempty"`
+	Published string         `xml:"published,omitempty"`
+	Updated   string         `xml:"updated"`
+}
+
+func marshalAtomFeed(id string, selfUrl string, title string, entries []atomFeedEntry, now time.Time) ([]byte, error) {
+	feed := atomFeedXml{
+		Xmlns: "http://www.w3.org/2005/Atom",
+		ID:    id,
+		Title: title,
+		Link:  atomLinkXml{Rel: "self", Href: selfUrl},
+		// entries without an author of their own inherit this one
+		Author:  atomAuthorXml{Name: "Glance"},
+		Entries: make([]atomEntryXml, 0, len(entries)),
+	}
+
+	var updated time.Time
+
+	for i := range entries {
+		entry := &entries[i]
+
+		// every entry needs an updated date, the time of the update that
+		// fetched it is the closest thing there is to one when it has no date
+		published := entry.Published
+		if published.IsZero() {
+			published = now
+		}
+
+		if published.After(updated) {
+			updated = published
+		}
+
+		xmlEntry := atomEntryXml{
+			ID:      entry.URL,
+			Title:   entry.Title,
+			Link:    atomLinkXml{Href: entry.URL},
+			Updated: published.UTC().Format(time.RFC3339),
+		}
+
+		if !entry.Published.IsZero() {
+			xmlEntry.Published = xmlEntry.Updated
+		}
+
+		if entry.Author != "" {
+			xmlEntry.Author = &atomAuthorXml{Name: entry.Author}
+		}
+
+		feed.Entries = append(feed.Entries, xmlEntry)
+	}
+
+	if updated.IsZero() {
+		updated = now
+	}
+
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	output, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), output...), nil
+}
+
+func (a *application) handleWidgetFeedRequest(w http.ResponseWriter, r *http.Request) {
+	widgetID, err := strconv.ParseUint(r.PathValue("widget"), 10, 64)
+	if err != nil {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	widget, exists := a.widgetByID[widgetID]
+	if !exists {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	exporting, ok := widget.(atomFeedWidget)
+	if !ok {
+		http.Error(w, "widget does not support being exposed as a feed", http.StatusBadRequest)
+		return
+	}
+
+	var title string
+	var entries []atomFeedEntry
+
+	// the page may never have been opened, in which case the widget wouldn't
+	// have anything in it yet
+	func() {
+		page := a.widgetPages[widgetID]
+		page.mu.Lock()
+		defer page.mu.Unlock()
+
+		now := time.Now()
+		if widget.requiresUpdate(&now) {
+			updateWidget(context.Background(), widget)
+		}
+
+		title, entries = exporting.atomFeed()
+	}()
+
+	feedPath := "/feed/widget/" + strconv.FormatUint(widgetID, 10)
+	output, err := marshalAtomFeed("urn:glance:widget:"+strconv.FormatUint(widgetID, 10), a.Config.Server.BaseURL+feedPath, title, entries, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(output))
+}
@@ -0,0 +1,126 @@
+package glance
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWidgetFeedRequest(t *testing.T) {
+	published := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": bilibiliTestFeed(
+			bilibiliTestItem("BV1", "First video", published),
+			bilibiliTestItem("BV2", "Second video", published.Add(-time.Hour)),
+			bilibiliTestItem("BV3", "Third video", published.Add(-2*time.Hour)),
+		),
+	})
+
+	config, err := newConfigFromYAML([]byte(`
+server:
+  base-url: https://glance.example
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: bilibili-videos
+            title: Followed
+            limit: 2
+            rsshuburls: [` + server.URL + `/feed]
+          - type: html
+            source: <p>not a feed</p>
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	app, err := newApplication(config)
+	if err != nil {
+		t.Fatalf("creating application: %v", err)
+	}
+
+	widgets := app.slugToPage["home"].Columns[0].Widgets
+
+	request := func(id string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/feed/widget/"+id, nil)
+		r.SetPathValue("widget", id)
+		recorder := httptest.NewRecorder()
+		app.handleWidgetFeedRequest(recorder, r)
+
+		return recorder
+	}
+
+	videosID := strconv.FormatUint(widgets[0].GetID(), 10)
+	response := request(videosID)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected a 200, got %d: %s", response.Code, response.Body.String())
+	}
+
+	if contentType := response.Header().Get("Content-Type"); contentType != "application/atom+xml; charset=utf-8" {
+		t.Errorf("expected an Atom content type, got %s", contentType)
+	}
+
+	var feed atomFeedXml
+	if err := xml.Unmarshal(response.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("expected valid XML, got %v:\n%s", err, response.Body.String())
+	}
+
+	if feed.XMLName.Space != "http://www.w3.org/2005/Atom" || feed.Title != "Followed" || feed.ID != "urn:glance:widget:"+videosID {
+		t.Errorf("unexpected feed %s %q %q", feed.XMLName.Space, feed.Title, feed.ID)
+	}
+
+	if want := "https://glance.example/feed/widget/" + videosID; feed.Link.Rel != "self" || feed.Link.Href != want {
+		t.Errorf("expected a self link to %s, got %+v", want, feed.Link)
+	}
+
+	if want := published.Format(time.RFC3339); feed.Updated != want {
+		t.Errorf("expected the feed to be updated at %s, got %s", want, feed.Updated)
+	}
+
+	// the widget's limit applies to the feed too
+	want := []atomEntryXml{
+		{
+			ID:        "https://www.bilibili.com/video/BV1",
+			Title:     "First video",
+			Link:      atomLinkXml{Href: "https://www.bilibili.com/video/BV1"},
+			Author:    &atomAuthorXml{Name: "Author"},
+			Published: published.Format(time.RFC3339),
+			Updated:   published.Format(time.RFC3339),
+		},
+		{
+			ID:        "https://www.bilibili.com/video/BV2",
+			Title:     "Second video",
+			Link:      atomLinkXml{Href: "https://www.bilibili.com/video/BV2"},
+			Author:    &atomAuthorXml{Name: "Author"},
+			Published: published.Add(-time.Hour).Format(time.RFC3339),
+			Updated:   published.Add(-time.Hour).Format(time.RFC3339),
+		},
+	}
+
+	if len(feed.Entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(feed.Entries))
+	}
+
+	for i := range want {
+		got := feed.Entries[i]
+		if got.ID != want[i].ID || got.Title != want[i].Title || got.Link != want[i].Link ||
+			got.Author == nil || *got.Author != *want[i].Author ||
+			got.Published != want[i].Published || got.Updated != want[i].Updated {
+			t.Errorf("entry %d: expected %+v, got %+v", i, want[i], got)
+		}
+	}
+
+	if code := request(strconv.FormatUint(widgets[1].GetID(), 10)).Code; code != http.StatusBadRequest {
+		t.Errorf("expected widgets that can't be exposed as feeds to get a 400, got %d", code)
+	}
+
+	if code := request("999999").Code; code != http.StatusNotFound {
+		t.Errorf("expected unknown widgets to get a 404, got %d", code)
+	}
+}
@@ -0,0 +1,107 @@
+package glance
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type authConfig struct {
+	Username string `yaml:"username"`
+	// bcrypt hash of the password, never the plain text password
+	Password  string   `yaml:"password"`
+	Token     string   `yaml:"token"`
+	Allowlist []string `yaml:"allowlist"`
+}
+
+var defaultAuthAllowlist = []string{"/api/healthz"}
+
+func (c *authConfig) isEnabled() bool {
+	return c.Username != "" || c.Token != ""
+}
+
+func (c *authConfig) validate() error {
+	if c.Username == "" && c.Password != "" {
+		return errors.New("auth: password is set but username is not")
+	}
+
+	if c.Username != "" {
+		if c.Password == "" {
+			return errors.New("auth: username is set but password is not")
+		}
+
+		if _, err := bcrypt.Cost([]byte(c.Password)); err != nil {
+			return errors.New("auth: password must be a bcrypt hash")
+		}
+	}
+
+	return nil
+}
+
+func (c *authConfig) isPathAllowlisted(path string) bool {
+	allowlist := c.Allowlist
+	if allowlist == nil {
+		allowlist = defaultAuthAllowlist
+	}
+
+	for _, allowed := range allowlist {
+		if strings.HasSuffix(allowed, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+		} else if path == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *authConfig) isRequestAuthorized(r *http.Request) bool {
+	if c.Token != "" {
+		if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(c.Token)) == 1 {
+				return true
+			}
+		}
+	}
+
+	if c.Username != "" {
+		if username, password, ok := r.BasicAuth(); ok {
+			usernameMatches := subtle.ConstantTimeCompare([]byte(username), []byte(c.Username)) == 1
+			// always compare the password, even when the username doesn't match,
+			// so that the response time doesn't reveal whether the username exists
+			passwordMatches := bcrypt.CompareHashAndPassword([]byte(c.Password), []byte(password)) == nil
+
+			if usernameMatches && passwordMatches {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *authConfig) middleware(next http.Handler) http.Handler {
+	if !c.isEnabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.isPathAllowlisted(r.URL.Path) || c.isRequestAuthorized(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Glance", charset="UTF-8"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Glance"`)
+		}
+
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
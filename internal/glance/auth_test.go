@@ -0,0 +1,162 @@
+package glance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	basic := &authConfig{Username: "admin", Password: string(hash)}
+	bearer := &authConfig{Token: "s3cret"}
+	both := &authConfig{Username: "admin", Password: string(hash), Token: "s3cret"}
+
+	tests := []struct {
+		name       string
+		config     *authConfig
+		path       string
+		setHeaders func(r *http.Request)
+		wantStatus int
+	}{
+		{
+			name:       "disabled",
+			config:     &authConfig{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no credentials",
+			config:     basic,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "correct basic credentials",
+			config:     basic,
+			setHeaders: func(r *http.Request) { r.SetBasicAuth("admin", "correct horse") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong password",
+			config:     basic,
+			setHeaders: func(r *http.Request) { r.SetBasicAuth("admin", "wrong") },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong username",
+			config:     basic,
+			setHeaders: func(r *http.Request) { r.SetBasicAuth("someone", "correct horse") },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "correct token",
+			config:     bearer,
+			setHeaders: func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong token",
+			config:     bearer,
+			setHeaders: func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "token without a scheme",
+			config:     bearer,
+			setHeaders: func(r *http.Request) { r.Header.Set("Authorization", "s3cret") },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "basic credentials when both are enabled",
+			config:     both,
+			setHeaders: func(r *http.Request) { r.SetBasicAuth("admin", "correct horse") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "token when both are enabled",
+			config:     both,
+			setHeaders: func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "default allowlist",
+			config:     basic,
+			path:       "/api/healthz",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "custom allowlist replaces the default",
+			config:     &authConfig{Token: "s3cret", Allowlist: []string{"/static/*"}},
+			path:       "/api/healthz",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "allowlisted prefix",
+			config:     &authConfig{Token: "s3cret", Allowlist: []string{"/static/*"}},
+			path:       "/static/main.css",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := test.path
+			if path == "" {
+				path = "/"
+			}
+
+			request := httptest.NewRequest("GET", path, nil)
+			if test.setHeaders != nil {
+				test.setHeaders(request)
+			}
+
+			recorder := httptest.NewRecorder()
+			test.config.middleware(next).ServeHTTP(recorder, request)
+
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("expected status %d, got %d", test.wantStatus, recorder.Code)
+			}
+
+			if recorder.Code == http.StatusUnauthorized && recorder.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected a WWW-Authenticate header with the 401 response")
+			}
+		})
+	}
+}
+
+func TestAuthConfigValidate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  authConfig
+		wantErr bool
+	}{
+		{"disabled", authConfig{}, false},
+		{"bcrypt hash", authConfig{Username: "admin", Password: string(hash)}, false},
+		{"token only", authConfig{Token: "s3cret"}, false},
+		{"plain text password", authConfig{Username: "admin", Password: "password"}, true},
+		{"username without password", authConfig{Username: "admin"}, true},
+		{"password without username", authConfig{Password: string(hash)}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.config.validate(); (err != nil) != test.wantErr {
+				t.Errorf("expected error: %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package glance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Fetches all feeds in a single request, returning them in the same order as
+// the given URLs regardless of the order the aggregator responded with. Each
+// feed gets decoded the same way as when it's fetched on its own, so limits
+// and tolerance of malformed items apply to every one of them
+func fetchBilibiliFeedsFromAggregator(ctx context.Context, client requestDoer, options bilibiliFetchOptions) ([]bilibiliFeedResponseJson, []error, error) {
+	body, err := json.Marshal(map[string][]string{"feeds": options.FeedUrls})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", options.Aggregator, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := doBilibiliFeedRequest(client, request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("aggregator: %w", err)
+	}
+	defer response.Body.Close()
+
+	var responseBody io.Reader = response.Body
+	if options.MaxFeedBytes > 0 {
+		// every feed can use up its own budget but the response can't go over
+		// all of them combined
+		budget := options.MaxFeedBytes * int64(len(options.FeedUrls))
+		responseBody = &budgetedReader{reader: responseBody, budget: budget, remaining: budget}
+	}
+
+	var aggregated struct {
+		Feeds []json.RawMessage `json:"feeds"`
+	}
+
+	if err := json.NewDecoder(responseBody).Decode(&aggregated); err != nil {
+		return nil, nil, fmt.Errorf("aggregator: %w", err)
+	}
+
+	responses := make([]bilibiliFeedResponseJson, len(options.FeedUrls))
+	errs := make([]error, len(options.FeedUrls))
+
+	for i := range options.FeedUrls {
+		errs[i] = errors.New("feed missing from the aggregator's response")
+	}
+
+	for _, raw := range aggregated.Feeds {
+		var meta struct {
+			URL   string `json:"url"`
+			Error string `json:"error"`
+		}
+
+		// without its URL there's no telling which feed it is
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			continue
+		}
+
+		feed, err := decodeAggregatedBilibiliFeed(raw, meta.URL, meta.Error, options)
+
+		for i := range options.FeedUrls {
+			if options.FeedUrls[i] == meta.URL {
+				responses[i], errs[i] = feed, err
+			}
+		}
+	}
+
+	return responses, errs, nil
+}
+
+func decodeAggregatedBilibiliFeed(raw json.RawMessage, feedUrl string, feedErr string, options bilibiliFetchOptions) (bilibiliFeedResponseJson, error) {
+	if feedErr != "" {
+		return bilibiliFeedResponseJson{}, errors.New(feedErr)
+	}
+
+	if options.MaxFeedBytes > 0 && int64(len(raw)) > options.MaxFeedBytes {
+		return bilibiliFeedResponseJson{}, fmt.Errorf("%w: larger than %d bytes", errFeedTooLarge, options.MaxFeedBytes)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+
+	return decodeBilibiliFeed(decoder, feedUrl, options.MaxFeedItems, options.Tolerant)
+}
@@ -0,0 +1,126 @@
+package glance
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func bilibiliTestFeedItems(count int) string {
+	items := make([]string, count)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{
+			"url": "https://www.bilibili.com/video/BV%d",
+			"title": "Video %d",
+			"date_published": "2024-05-10T12:00:00Z",
+			"authors": [{"name": "Author"}]
+		}`, i, i)
+	}
+
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func TestFetchBilibiliFeedsFromAggregator(t *testing.T) {
+	const (
+		large     = "https://rsshub.example/bilibili/user/video/1"
+		malformed = "https://rsshub.example/bilibili/user/video/2"
+		failed    = "https://rsshub.example/bilibili/user/video/3"
+		missing   = "https://rsshub.example/bilibili/user/video/4"
+		small     = "https://rsshub.example/bilibili/user/video/5"
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"feeds": [
+			{"url": %q, "title": "Large", "items": %s},
+			{"url": %q, "items": [{"url": "https://www.bilibili.com/video/BVbad", "title": "Bad", "date_published": "yesterday"}, %s]},
+			{"url": %q, "error": "rsshub is down"},
+			{"url": %q, "items": %s}
+		]}`,
+			large, bilibiliTestFeedItems(5),
+			malformed, strings.Trim(bilibiliTestFeedItems(1), "[]"),
+			failed,
+			small, bilibiliTestFeedItems(1),
+		)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		options bilibiliFetchOptions
+		// the number of videos of each feed, -1 if it should have failed
+		want     []int
+		tooLarge []bool
+	}{
+		{
+			name: "default",
+			want: []int{5, -1, -1, -1, 1},
+		},
+		{
+			name:    "tolerant",
+			options: bilibiliFetchOptions{Tolerant: true},
+			want:    []int{5, 1, -1, -1, 1},
+		},
+		{
+			name:     "max feed items",
+			options:  bilibiliFetchOptions{MaxFeedItems: 2, Tolerant: true},
+			want:     []int{-1, 1, -1, -1, 1},
+			tooLarge: []bool{true, false, false, false, false},
+		},
+		{
+			name:     "max feed bytes",
+			options:  bilibiliFetchOptions{MaxFeedBytes: 400, Tolerant: true},
+			want:     []int{-1, 1, -1, -1, 1},
+			tooLarge: []bool{true, false, false, false, false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := test.options
+			options.Aggregator = server.URL
+			options.FeedUrls = []string{large, malformed, failed, missing, small}
+
+			results, errs, err := fetchBilibiliFeeds(options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for i, want := range test.want {
+				if want == -1 {
+					if errs[i] == nil {
+						t.Errorf("expected feed %d to fail", i)
+					}
+				} else if errs[i] != nil {
+					t.Errorf("expected feed %d not to fail, got %v", i, errs[i])
+				} else if len(results[i]) != want {
+					t.Errorf("expected feed %d to have %d videos, got %d", i, want, len(results[i]))
+				}
+
+				if test.tooLarge != nil && test.tooLarge[i] != errors.Is(errs[i], errFeedTooLarge) {
+					t.Errorf("expected feed %d to be too large: %v, got %v", i, test.tooLarge[i], errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFetchBilibiliFeedsFromAggregatorBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"feeds": [{"url": "https://rsshub.example/1", "items": %s}]}`, bilibiliTestFeedItems(100))
+	}))
+	defer server.Close()
+
+	// the response as a whole is over the budget of all feeds combined
+	_, _, err := fetchBilibiliFeeds(bilibiliFetchOptions{
+		Aggregator:   server.URL,
+		FeedUrls:     []string{"https://rsshub.example/1"},
+		MaxFeedBytes: 1024,
+	})
+
+	if !errors.Is(err, errFeedTooLarge) {
+		t.Errorf("expected the response to be too large, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package glance
+
+import (
+	"maps"
+	"strings"
+	"unicode"
+)
+
+func (v bilibiliVideoList) notIn(seen map[string]struct{}) bilibiliVideoList {
+	unseen := make(bilibiliVideoList, 0, len(v))
+
+	for i := range v {
+		if _, exists := seen[v[i].Url]; !exists {
+			unseen = append(unseen, v[i])
+		}
+	}
+
+	return unseen
+}
+
+// Keeps only the first of the videos that share the same key, which for lists
+// sorted by newest is the most recent one. When keeping the best thumbnail, a
+// later duplicate takes the place of the first one if both of their
+// resolutions are known and its thumbnail is larger
+func (v bilibiliVideoList) deduplicate(by string, keepBestThumbnail bool, mergeMetadata bool) bilibiliVideoList {
+	seen := make(map[string]int, len(v))
+	deduplicated := make(bilibiliVideoList, 0, len(v))
+
+	for i := range v {
+		key := v[i].Url
+
+		switch by {
+		case "title":
+			key = "title:" + v[i].Title
+		case "normalized-title":
+			// titles made up of nothing but decoration would all collapse into one
+			if normalized := normalizeBilibiliTitle(v[i].Title); normalized != "" {
+				key = "title:" + normalized
+			}
+		}
+
+		if kept, exists := seen[key]; exists {
+			current := deduplicated[kept].thumbnailPixels
+
+			if keepBestThumbnail && current > 0 && v[i].thumbnailPixels > current {
+				if mergeMetadata {
+					v[i].fillMissingFrom(&deduplicated[kept])
+				}
+
+				deduplicated[kept] = v[i]
+			} else if mergeMetadata {
+				deduplicated[kept].fillMissingFrom(&v[i])
+			}
+
+			continue
+		}
+
+		seen[key] = len(deduplicated)
+		deduplicated = append(deduplicated, v[i])
+	}
+
+	return deduplicated
+}
+
+// Fills in the fields of the video that its feed left empty with those of a
+// duplicate of it from another feed
+func (v *bilibiliVideo) fillMissingFrom(other *bilibiliVideo) {
+	if v.ThumbnailUrl == "" {
+		v.ThumbnailUrl = other.ThumbnailUrl
+		v.DirectThumbnailUrl = other.DirectThumbnailUrl
+		v.thumbnailPixels = other.thumbnailPixels
+	}
+
+	if v.Author == "" {
+		v.Author = other.Author
+	}
+
+	if v.AuthorUrl == "" {
+		v.AuthorUrl = other.AuthorUrl
+	}
+
+	if v.AuthorAvatarUrl == "" {
+		v.AuthorAvatarUrl = other.AuthorAvatarUrl
+	}
+
+	if v.TimePosted.IsZero() {
+		v.TimePosted = other.TimePosted
+		v.dateHasNoOffset = other.dateHasNoOffset
+	}
+
+	if v.Duration == 0 {
+		v.Duration = other.Duration
+	}
+
+	if v.Status == "" {
+		v.Status = other.Status
+	}
+
+	if len(other.Extra) > 0 {
+		// the extra fields of the video may be shared with the cached copy
+		merged := make(map[string]any, len(v.Extra)+len(other.Extra))
+		maps.Copy(merged, other.Extra)
+		maps.Copy(merged, v.Extra)
+		v.Extra = merged
+	}
+
+	if len(v.ExtraLabels) == 0 {
+		v.ExtraLabels = other.ExtraLabels
+	}
+}
+
+// Marks or drops every video after the first with the same thumbnail
+func (v bilibiliVideoList) flagDuplicateThumbnails(drop bool) bilibiliVideoList {
+	seen := make(map[string]struct{}, len(v))
+	kept := make(bilibiliVideoList, 0, len(v))
+
+	for i := range v {
+		// proxied and direct URLs of the same image would look different
+		thumbnailUrl := v[i].DirectThumbnailUrl
+		if thumbnailUrl == "" {
+			thumbnailUrl = v[i].ThumbnailUrl
+		}
+
+		if thumbnailUrl != "" {
+			if _, exists := seen[thumbnailUrl]; exists {
+				if drop {
+					continue
+				}
+
+				v[i].LikelyDuplicate = true
+			}
+
+			seen[thumbnailUrl] = struct{}{}
+		}
+
+		kept = append(kept, v[i])
+	}
+
+	return kept
+}
+
+// Lowercases the title and leaves out everything that isn't a letter or a
+// number, such as whitespace, punctuation, brackets like 【】 and emoji
+func normalizeBilibiliTitle(title string) string {
+	var normalized strings.Builder
+	normalized.Grow(len(title))
+
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			normalized.WriteRune(r)
+		}
+	}
+
+	return normalized.String()
+}
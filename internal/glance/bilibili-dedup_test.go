@@ -0,0 +1,253 @@
+package glance
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBilibiliVideoListDeduplicate(t *testing.T) {
+	tests := []struct {
+		name     string
+		by       string
+		videos   bilibiliVideoList
+		wantUrls []string
+	}{
+		{
+			name: "by url",
+			videos: bilibiliVideoList{
+				{Url: "a", Title: "Same"},
+				{Url: "b", Title: "Same"},
+				{Url: "a", Title: "First again"},
+			},
+			wantUrls: []string{"a", "b"},
+		},
+		{
+			name: "by title",
+			by:   "title",
+			videos: bilibiliVideoList{
+				{Url: "a", Title: "Same"},
+				{Url: "b", Title: "Same"},
+				{Url: "c", Title: "same"},
+			},
+			wantUrls: []string{"a", "c"},
+		},
+		{
+			name: "by normalized title",
+			by:   "normalized-title",
+			videos: bilibiliVideoList{
+				{Url: "a", Title: "【Hello, World!】"},
+				{Url: "b", Title: "hello world"},
+				{Url: "c", Title: "Hello World 2"},
+			},
+			wantUrls: []string{"a", "c"},
+		},
+		{
+			name: "titles without letters or numbers",
+			by:   "normalized-title",
+			videos: bilibiliVideoList{
+				{Url: "a", Title: "!!!"},
+				{Url: "b", Title: "???"},
+			},
+			wantUrls: []string{"a", "b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deduplicated := test.videos.deduplicate(test.by, false, false)
+
+			if urls := bilibiliVideoUrls(deduplicated); !slices.Equal(urls, test.wantUrls) {
+				t.Fatalf("expected %v, got %v", test.wantUrls, urls)
+			}
+
+			// the first of the duplicates is the one that's kept
+			if deduplicated[0].Title != test.videos[0].Title {
+				t.Errorf("expected the first video to be kept, got %q", deduplicated[0].Title)
+			}
+		})
+	}
+}
+
+func TestNormalizeBilibiliTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Hello, World!", "helloworld"},
+		{"【官方】第 1 集 🎉", "官方第1集"},
+		{"   ", ""},
+	}
+
+	for _, test := range tests {
+		if got := normalizeBilibiliTitle(test.title); got != test.want {
+			t.Errorf("normalizeBilibiliTitle(%q): expected %q, got %q", test.title, test.want, got)
+		}
+	}
+}
+
+func TestBilibiliVideoListDeduplicateKeepsBestThumbnail(t *testing.T) {
+	tests := []struct {
+		name              string
+		keepBestThumbnail bool
+		videos            bilibiliVideoList
+		want              string
+	}{
+		{
+			name:              "larger thumbnail replaces the first",
+			keepBestThumbnail: true,
+			videos: bilibiliVideoList{
+				{Url: "a", ThumbnailUrl: "small", thumbnailPixels: 100},
+				{Url: "a", ThumbnailUrl: "large", thumbnailPixels: 400},
+			},
+			want: "large",
+		},
+		{
+			name:              "smaller thumbnail doesn't",
+			keepBestThumbnail: true,
+			videos: bilibiliVideoList{
+				{Url: "a", ThumbnailUrl: "large", thumbnailPixels: 400},
+				{Url: "a", ThumbnailUrl: "small", thumbnailPixels: 100},
+			},
+			want: "large",
+		},
+		{
+			name:              "unknown resolution keeps the first",
+			keepBestThumbnail: true,
+			videos: bilibiliVideoList{
+				{Url: "a", ThumbnailUrl: "first"},
+				{Url: "a", ThumbnailUrl: "second", thumbnailPixels: 400},
+			},
+			want: "first",
+		},
+		{
+			name: "disabled",
+			videos: bilibiliVideoList{
+				{Url: "a", ThumbnailUrl: "small", thumbnailPixels: 100},
+				{Url: "a", ThumbnailUrl: "large", thumbnailPixels: 400},
+			},
+			want: "small",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deduplicated := test.videos.deduplicate("", test.keepBestThumbnail, false)
+
+			if len(deduplicated) != 1 {
+				t.Fatalf("expected a single video, got %d", len(deduplicated))
+			}
+
+			if deduplicated[0].ThumbnailUrl != test.want {
+				t.Errorf("expected thumbnail %q, got %q", test.want, deduplicated[0].ThumbnailUrl)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideoListDeduplicateMergesMetadata(t *testing.T) {
+	posted := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	videos := bilibiliVideoList{
+		{Url: "a", Author: "Someone", Extra: map[string]any{"views": 1}},
+		{Url: "a", Author: "Someone else", ThumbnailUrl: "thumbnail", Duration: time.Minute, TimePosted: posted, Extra: map[string]any{"views": 2, "likes": 3}},
+	}
+
+	tests := []struct {
+		name  string
+		merge bool
+		check func(t *testing.T, video bilibiliVideo)
+	}{
+		{
+			name:  "merged",
+			merge: true,
+			check: func(t *testing.T, video bilibiliVideo) {
+				if video.Author != "Someone" {
+					t.Errorf("expected the kept author to stay, got %q", video.Author)
+				}
+
+				if video.ThumbnailUrl != "thumbnail" || video.Duration != time.Minute || !video.TimePosted.Equal(posted) {
+					t.Errorf("expected the missing fields to be filled in, got %+v", video)
+				}
+
+				if video.Extra["views"] != 1 || video.Extra["likes"] != 3 {
+					t.Errorf("expected the extra fields to be merged with the kept ones winning, got %v", video.Extra)
+				}
+			},
+		},
+		{
+			name: "not merged",
+			check: func(t *testing.T, video bilibiliVideo) {
+				if video.ThumbnailUrl != "" || video.Duration != 0 {
+					t.Errorf("expected the fields to be left alone, got %+v", video)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deduplicated := slices.Clone(videos).deduplicate("", false, test.merge)
+
+			if len(deduplicated) != 1 {
+				t.Fatalf("expected a single video, got %d", len(deduplicated))
+			}
+
+			test.check(t, deduplicated[0])
+		})
+	}
+}
+
+func TestBilibiliVideoListFlagDuplicateThumbnails(t *testing.T) {
+	videos := func() bilibiliVideoList {
+		return bilibiliVideoList{
+			{Url: "https://www.bilibili.com/video/BV1", ThumbnailUrl: "https://proxy.example/1.jpg", DirectThumbnailUrl: "https://i0.hdslb.com/spam.jpg"},
+			{Url: "https://www.bilibili.com/video/BV2", ThumbnailUrl: "https://i0.hdslb.com/other.jpg"},
+			// the same image through another proxy shard
+			{Url: "https://www.bilibili.com/video/BV3", ThumbnailUrl: "https://proxy2.example/1.jpg", DirectThumbnailUrl: "https://i0.hdslb.com/spam.jpg"},
+			{Url: "https://www.bilibili.com/video/BV4"},
+			{Url: "https://www.bilibili.com/video/BV5"},
+			{Url: "https://www.bilibili.com/video/BV6", ThumbnailUrl: "https://i0.hdslb.com/spam.jpg"},
+		}
+	}
+
+	marked := videos().flagDuplicateThumbnails(false)
+
+	if want := bilibiliVideoUrls(videos()); !slices.Equal(bilibiliVideoUrls(marked), want) {
+		t.Errorf("expected every video to be kept when marking, got %v", bilibiliVideoUrls(marked))
+	}
+
+	// videos without a thumbnail have nothing in common
+	wantMarked := []bool{false, false, true, false, false, true}
+	for i := range marked {
+		if marked[i].LikelyDuplicate != wantMarked[i] {
+			t.Errorf("video %d: expected to be marked as a likely duplicate: %t", i, wantMarked[i])
+		}
+	}
+
+	dropped := videos().flagDuplicateThumbnails(true)
+	want := []string{
+		"https://www.bilibili.com/video/BV1",
+		"https://www.bilibili.com/video/BV2",
+		"https://www.bilibili.com/video/BV4",
+		"https://www.bilibili.com/video/BV5",
+	}
+
+	if got := bilibiliVideoUrls(dropped); !slices.Equal(got, want) {
+		t.Errorf("expected %v to be kept when dropping, got %v", want, got)
+	}
+}
+
+func TestBilibiliVideosFlagDuplicateThumbnailsRender(t *testing.T) {
+	widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\nflag-duplicate-thumbnails: mark\n")
+	widget.ContentAvailable = true
+	widget.Videos = bilibiliVideoList{
+		{Title: "Original", Url: "https://www.bilibili.com/video/BV1", ThumbnailUrl: "https://i0.hdslb.com/spam.jpg", TimePosted: time.Now()},
+		{Title: "Copy", Url: "https://www.bilibili.com/video/BV2", ThumbnailUrl: "https://i0.hdslb.com/spam.jpg", TimePosted: time.Now()},
+	}.flagDuplicateThumbnails(false)
+
+	if count := strings.Count(string(widget.Render()), "likely-duplicate"); count != 1 {
+		t.Errorf("expected a single card to be marked as a likely duplicate, got %d", count)
+	}
+}
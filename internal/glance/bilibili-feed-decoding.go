@@ -0,0 +1,209 @@
+package glance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// Same as bilibiliFeedResponseJson but with the items left undecoded so
+// that they can be decoded one by one, skipping any malformed ones
+type bilibiliFeedResponseRawJson struct {
+	Version     string            `json:"version"`
+	Title       string            `json:"title"`
+	HomePageURL string            `json:"home_page_url"`
+	Description string            `json:"description"`
+	Language    string            `json:"language"`
+	Items       []json.RawMessage `json:"items"`
+}
+
+func decodeBilibiliFeedTolerantlyTask(client requestDoer) func(*http.Request) (bilibiliFeedResponseJson, error) {
+	return func(request *http.Request) (bilibiliFeedResponseJson, error) {
+		raw, err := decodeJsonFromRequest[bilibiliFeedResponseRawJson](client, request)
+		if err != nil {
+			return bilibiliFeedResponseJson{}, err
+		}
+
+		response := bilibiliFeedResponseJson{
+			Version:     raw.Version,
+			Title:       raw.Title,
+			HomePageURL: raw.HomePageURL,
+			Description: raw.Description,
+			Language:    raw.Language,
+			Items:       make([]bilibiliFeedItemJson, 0, len(raw.Items)),
+		}
+
+		for i := range raw.Items {
+			var item bilibiliFeedItemJson
+
+			if err := json.Unmarshal(raw.Items[i], &item); err != nil {
+				slog.Warn("Skipping malformed bilibili feed item", "url", request.URL, "index", i, "error", err)
+				continue
+			}
+
+			response.Items = append(response.Items, item)
+		}
+
+		return response, nil
+	}
+}
+
+var errFeedTooLarge = errors.New("feed exceeds the configured size limit")
+
+// Stops reading once the budget is used up so that huge responses never end
+// up fully in memory
+type budgetedReader struct {
+	reader    io.Reader
+	budget    int64
+	remaining int64
+}
+
+func (r *budgetedReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		// the body could end exactly at the budget, which is fine
+		var probe [1]byte
+		if n, err := r.reader.Read(probe[:]); n == 0 && err == io.EOF {
+			return 0, io.EOF
+		}
+
+		return 0, fmt.Errorf("%w: larger than %d bytes", errFeedTooLarge, r.budget)
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+
+	return n, err
+}
+
+// Decodes the feed as it's being read, aborting as soon as it goes over
+// either of the limits, a limit of 0 means no limit
+func decodeBilibiliFeedStreamingTask(client requestDoer, maxItems int, maxBytes int64, tolerant bool) func(*http.Request) (bilibiliFeedResponseJson, error) {
+	return func(request *http.Request) (bilibiliFeedResponseJson, error) {
+		response, err := doBilibiliFeedRequest(client, request)
+		if err != nil {
+			return bilibiliFeedResponseJson{}, err
+		}
+		defer response.Body.Close()
+
+		var body io.Reader = response.Body
+		if maxBytes > 0 {
+			body = &budgetedReader{reader: body, budget: maxBytes, remaining: maxBytes}
+		}
+
+		return decodeBilibiliFeed(json.NewDecoder(body), request.URL.String(), maxItems, tolerant)
+	}
+}
+
+func doBilibiliFeedRequest(client requestDoer, request *http.Request) (*http.Response, error) {
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 256))
+
+		return nil, fmt.Errorf(
+			"unexpected status code %d for %s, response: %s",
+			response.StatusCode,
+			request.URL,
+			body,
+		)
+	}
+
+	return response, nil
+}
+
+// Decodes a single feed object, skipping any properties it doesn't know about
+func decodeBilibiliFeed(decoder *json.Decoder, source string, maxItems int, tolerant bool) (bilibiliFeedResponseJson, error) {
+	var feed bilibiliFeedResponseJson
+
+	if err := expectJsonDelim(decoder, '{'); err != nil {
+		return feed, err
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return feed, err
+		}
+
+		switch token {
+		case "version":
+			err = decoder.Decode(&feed.Version)
+		case "title":
+			err = decoder.Decode(&feed.Title)
+		case "home_page_url":
+			err = decoder.Decode(&feed.HomePageURL)
+		case "description":
+			err = decoder.Decode(&feed.Description)
+		case "language":
+			err = decoder.Decode(&feed.Language)
+		case "items":
+			feed.Items, err = decodeBilibiliFeedItemsStreaming(decoder, source, maxItems, tolerant)
+		default:
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+
+		if err != nil {
+			return feed, err
+		}
+	}
+
+	return feed, nil
+}
+
+func decodeBilibiliFeedItemsStreaming(decoder *json.Decoder, source string, maxItems int, tolerant bool) ([]bilibiliFeedItemJson, error) {
+	if err := expectJsonDelim(decoder, '['); err != nil {
+		return nil, err
+	}
+
+	items := make([]bilibiliFeedItemJson, 0)
+
+	for index := 0; decoder.More(); index++ {
+		if maxItems > 0 && index >= maxItems {
+			return nil, fmt.Errorf("%w: more than %d items", errFeedTooLarge, maxItems)
+		}
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+
+		var item bilibiliFeedItemJson
+		if err := json.Unmarshal(raw, &item); err != nil {
+			if !tolerant {
+				return nil, err
+			}
+
+			slog.Warn("Skipping malformed bilibili feed item", "url", source, "index", index, "error", err)
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	return items, expectJsonDelim(decoder, ']')
+}
+
+func expectJsonDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("expected %q in JSON but got %v", delim, token)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchBilibiliFeedsTolerant(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": bilibiliTestFeed(
+			bilibiliTestItem("BV1", "First video", time.Now()),
+			`{"url": "https://www.bilibili.com/video/BV2", "title": "Malformed video", "date_published": "yesterday"}`,
+			bilibiliTestItem("BV3", "Third video", time.Now().Add(-time.Hour)),
+		),
+	})
+
+	tests := []struct {
+		name    string
+		options bilibiliFetchOptions
+		wantErr bool
+	}{
+		{name: "strict", wantErr: true},
+		{name: "tolerant", options: bilibiliFetchOptions{Tolerant: true}},
+		{name: "tolerant with limits", options: bilibiliFetchOptions{Tolerant: true, MaxFeedItems: 10, MaxFeedBytes: 1 << 20}},
+		{name: "strict with limits", options: bilibiliFetchOptions{MaxFeedItems: 10}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := test.options
+			options.FeedUrls = []string{server.URL + "/feed"}
+
+			results, errs, err := fetchBilibiliFeeds(options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if test.wantErr {
+				if errs[0] == nil {
+					t.Error("expected the feed to fail")
+				}
+				return
+			}
+
+			if errs[0] != nil {
+				t.Fatalf("expected the feed not to fail, got %v", errs[0])
+			}
+
+			want := []string{"https://www.bilibili.com/video/BV1", "https://www.bilibili.com/video/BV3"}
+			if got := bilibiliVideoUrls(results[0]); !slices.Equal(got, want) {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosTolerantRender(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": bilibiliTestFeed(
+			bilibiliTestItem("BV1", "First video", time.Now()),
+			`{"url": "https://www.bilibili.com/video/BV2", "title": "Malformed video", "date_published": "yesterday"}`,
+		),
+	})
+
+	widget := newTestBilibiliVideosWidget(t, "rsshuburls: ["+server.URL+"/feed]\ntolerant: true\n")
+	widget.update(context.Background())
+
+	rendered := string(widget.Render())
+
+	if !strings.Contains(rendered, "First video") || strings.Contains(rendered, "Malformed video") {
+		t.Errorf("expected only the well-formed video to be shown, got:\n%s", rendered)
+	}
+}
+
+func TestDecodeBilibiliFeedStreamingLimits(t *testing.T) {
+	items := make([]string, 5)
+	for i := range items {
+		items[i] = bilibiliTestItem(fmt.Sprintf("BV%d", i), fmt.Sprintf("Video %d", i), time.Now())
+	}
+
+	feed := bilibiliTestFeed(items...)
+	server := newTestBilibiliFeedServer(t, map[string]string{"/feed": feed})
+
+	tests := []struct {
+		name     string
+		maxItems int
+		maxBytes int64
+		wantErr  string
+	}{
+		{name: "no limits"},
+		{name: "within the limits", maxItems: 5, maxBytes: int64(len(feed))},
+		{name: "over the item budget", maxItems: 3, wantErr: "more than 3 items"},
+		{name: "over the byte budget", maxBytes: 100, wantErr: "larger than 100 bytes"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, err := http.NewRequest("GET", server.URL+"/feed", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := decodeBilibiliFeedStreamingTask(server.Client(), test.maxItems, test.maxBytes, false)(request)
+
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if len(decoded.Items) != len(items) {
+					t.Errorf("expected %d items, got %d", len(items), len(decoded.Items))
+				}
+				return
+			}
+
+			if !errors.Is(err, errFeedTooLarge) || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("expected a too large error mentioning %q, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,518 @@
+package glance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type bilibiliFeedItemJson struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url"`
+	Title         string    `json:"title"`
+	ContentHTML   string    `json:"content_html"`
+	Preview       string    `json:"preview"`
+	DatePublished time.Time `json:"date_published"` // 使用 time.Time 类型来解析日期
+	Authors       []struct {
+		Name   string `json:"name"`
+		URL    string `json:"url"`
+		Avatar string `json:"avatar"`
+	}
+	Attachments []struct {
+		DurationInSeconds float64 `json:"duration_in_seconds"`
+	} `json:"attachments"`
+	Tags []string `json:"tags"`
+
+	// kept around so that extensions only get decoded when they're used
+	raw json.RawMessage
+	// the date had no offset and was interpreted as UTC
+	dateHasNoOffset bool
+}
+
+func (i *bilibiliFeedItemJson) UnmarshalJSON(data []byte) error {
+	type bilibiliFeedItemJsonAlias bilibiliFeedItemJson
+
+	// the date gets decoded separately since some routes leave out its offset
+	item := struct {
+		*bilibiliFeedItemJsonAlias
+		DatePublished string `json:"date_published"`
+	}{
+		bilibiliFeedItemJsonAlias: (*bilibiliFeedItemJsonAlias)(i),
+	}
+
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+
+	var err error
+	i.DatePublished, i.dateHasNoOffset, err = parseBilibiliFeedDate(item.DatePublished)
+	if err != nil {
+		return err
+	}
+
+	i.raw = data
+	return nil
+}
+
+var bilibiliOffsetlessDateLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+// Parses RFC 3339 dates, as well as dates without an offset, which get
+// interpreted as UTC until the timezone of their feed gets applied
+func parseBilibiliFeedDate(value string) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+
+	if date, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return date, false, nil
+	}
+
+	for _, layout := range bilibiliOffsetlessDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true, nil
+		}
+	}
+
+	return time.Time{}, false, fmt.Errorf("invalid date_published %q", value)
+}
+
+// Returns the duration of the first attachment that has one, or 0 if none do
+func (i *bilibiliFeedItemJson) duration() time.Duration {
+	for _, attachment := range i.Attachments {
+		if attachment.DurationInSeconds > 0 {
+			return time.Duration(attachment.DurationInSeconds * float64(time.Second))
+		}
+	}
+
+	return 0
+}
+
+// Returns either live or premiere for items that are live streams or upcoming
+// premieres, based on the item's tags or where it links to
+func (i *bilibiliFeedItemJson) status() string {
+	for _, tag := range i.Tags {
+		switch strings.ToLower(strings.TrimSpace(tag)) {
+		case "live", "直播":
+			return "live"
+		case "premiere", "首映":
+			return "premiere"
+		}
+	}
+
+	if parsedUrl, err := url.Parse(i.URL); err == nil && parsedUrl.Hostname() == "live.bilibili.com" {
+		return "live"
+	}
+
+	return ""
+}
+
+// Returns the values of the given extension fields, which are dot separated
+// paths starting with the name of the extension object, e.g. _bilibili.views
+func (i *bilibiliFeedItemJson) extensionFields(paths []string) map[string]any {
+	var object map[string]any
+	if err := json.Unmarshal(i.raw, &object); err != nil {
+		return nil
+	}
+
+	fields := make(map[string]any, len(paths))
+
+	for _, path := range paths {
+		var value any = object
+
+		for _, key := range strings.Split(path, ".") {
+			nested, ok := value.(map[string]any)
+			if !ok {
+				value = nil
+				break
+			}
+
+			value = nested[key]
+		}
+
+		if value != nil {
+			fields[path] = value
+		}
+	}
+
+	return fields
+}
+
+type bilibiliFeedResponseJson struct {
+	Version     string                 `json:"version"`
+	Title       string                 `json:"title"`
+	HomePageURL string                 `json:"home_page_url"`
+	Description string                 `json:"description"`
+	Language    string                 `json:"language"`
+	Items       []bilibiliFeedItemJson `json:"items"`
+}
+
+var (
+	bilibiliSpaceUIDPattern = regexp.MustCompile(`space\.bilibili\.com/(\d+)`)
+	bilibiliRouteUIDPattern = regexp.MustCompile(`/bilibili/user/[a-z-]+/(\d+)`)
+	bilibiliImageSrcPattern = regexp.MustCompile(`<img[^>]+src="([^"]+)"`)
+	bilibiliImageTagPattern = regexp.MustCompile(`<img[^>]*>`)
+	// attributes of the image tag, e.g. width="672" height="378"
+	bilibiliImageWidthPattern  = regexp.MustCompile(`\bwidth="?(\d+)`)
+	bilibiliImageHeightPattern = regexp.MustCompile(`\bheight="?(\d+)`)
+	// sizes requested from bilibili's image CDN, e.g. cover.jpg@672w_378h.webp
+	bilibiliImageSizeSuffixPattern = regexp.MustCompile(`@(\d+)w_(\d+)h`)
+)
+
+// Returns the resolution of the first image in the content as the number of
+// pixels in it, based on its attributes or on the size requested in its URL
+func bilibiliThumbnailPixels(contentHTML string, imageUrl string) int {
+	if tag := bilibiliImageTagPattern.FindString(contentHTML); tag != "" {
+		width := bilibiliImageWidthPattern.FindStringSubmatch(tag)
+		height := bilibiliImageHeightPattern.FindStringSubmatch(tag)
+
+		if width != nil && height != nil {
+			w, _ := strconv.Atoi(width[1])
+			h, _ := strconv.Atoi(height[1])
+
+			if w > 0 && h > 0 {
+				return w * h
+			}
+		}
+	}
+
+	if size := bilibiliImageSizeSuffixPattern.FindStringSubmatch(imageUrl); size != nil {
+		w, _ := strconv.Atoi(size[1])
+		h, _ := strconv.Atoi(size[2])
+		return w * h
+	}
+
+	return 0
+}
+
+// Attempts to find the uploader's UID from the feed's home page URL, which
+// RSSHub sets to the uploader's space, or from the RSSHub route itself
+func deriveBilibiliUploaderUID(homePageUrl string, feedUrl string) string {
+	if matches := bilibiliSpaceUIDPattern.FindStringSubmatch(homePageUrl); len(matches) == 2 {
+		return matches[1]
+	}
+
+	if matches := bilibiliRouteUIDPattern.FindStringSubmatch(feedUrl); len(matches) == 2 {
+		return matches[1]
+	}
+
+	return ""
+}
+
+type bilibiliFetchOptions struct {
+	FeedUrls            []string
+	VideoUrlTemplate    string
+	IncludeShorts       bool
+	ImageProxy          string
+	RequireThumbnail    bool
+	MinDuration         time.Duration
+	DropUnknownDuration bool
+	AuthorUrlTemplate   string
+	AvatarUrlTemplate   string
+	Tolerant            bool
+	BlockAuthors        []string
+	ExtensionFields     []string
+	AuthorAliases       map[string]string
+	Client              *http.Client
+	MaxFeedItems        int
+	MaxFeedBytes        int64
+	HoverPreview        bool
+	ValidateItems       bool
+	Aggregator          string
+	Concurrency         *concurrencyTracker
+	// overrides the fixed number of workers when set
+	ConcurrencyLimit *adaptiveConcurrencyLimit
+	Context          context.Context
+}
+
+// Returns the videos of each feed in the same order as the feed URLs, along
+// with the error of each feed that failed to be fetched
+func fetchBilibiliFeeds(options bilibiliFetchOptions) ([]bilibiliVideoList, []error, error) {
+	requests := make([]*http.Request, 0, len(options.FeedUrls))
+
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for _, feedUrl := range options.FeedUrls {
+		request, _ := http.NewRequestWithContext(ctx, "GET", feedUrl, nil)
+		requests = append(requests, request)
+	}
+
+	client := defaultHTTPClient
+	if options.Client != nil {
+		client = options.Client
+	}
+
+	var responses []bilibiliFeedResponseJson
+	var errs []error
+	var err error
+
+	if options.Aggregator != "" {
+		responses, errs, err = fetchBilibiliFeedsFromAggregator(ctx, client, options)
+	} else {
+		task := decodeJsonFromRequestTask[bilibiliFeedResponseJson](client)
+		if options.MaxFeedItems > 0 || options.MaxFeedBytes > 0 {
+			task = decodeBilibiliFeedStreamingTask(client, options.MaxFeedItems, options.MaxFeedBytes, options.Tolerant)
+		} else if options.Tolerant {
+			task = decodeBilibiliFeedTolerantlyTask(client)
+		}
+
+		workers := 30
+		if options.ConcurrencyLimit != nil {
+			workers = options.ConcurrencyLimit.workers()
+			task = withAdaptiveConcurrencyRecording(options.ConcurrencyLimit, task)
+		}
+
+		job := newJob(task, requests).withWorkers(workers).withConcurrencyTracker(options.Concurrency)
+		responses, errs, err = workerPoolDo(job)
+
+		if options.ConcurrencyLimit != nil {
+			options.ConcurrencyLimit.adjust()
+		}
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]bilibiliVideoList, len(responses))
+
+	for i := range responses {
+		if errs[i] != nil {
+			if !errors.Is(errs[i], errNotModified) {
+				slog.Error("Failed to fetch bilibili feed", "rsshub url", options.FeedUrls[i], "error", errs[i])
+			}
+
+			continue
+		}
+
+		results[i] = bilibiliVideosFromFeed(options, options.FeedUrls[i], &responses[i])
+	}
+
+	return results, errs, nil
+}
+
+// Turns the items of a fetched feed into videos, leaving out those that the
+// options filter out
+func bilibiliVideosFromFeed(options bilibiliFetchOptions, feedUrl string, response *bilibiliFeedResponseJson) bilibiliVideoList {
+	videos := make(bilibiliVideoList, 0, len(response.Items))
+
+	var channelUrl, channelAvatarUrl string
+	if uid := deriveBilibiliUploaderUID(response.HomePageURL, feedUrl); uid != "" {
+		uidReplacer := strings.NewReplacer("{UID}", uid, "{uid}", uid)
+		channelUrl = uidReplacer.Replace(options.AuthorUrlTemplate)
+
+		if options.AvatarUrlTemplate != "" {
+			channelAvatarUrl = uidReplacer.Replace(options.AvatarUrlTemplate)
+		}
+	}
+
+	for j := range response.Items {
+		v := &response.Items[j]
+		// 查找所有匹配项
+		matches := bilibiliImageSrcPattern.FindAllStringSubmatch(v.ContentHTML, -1)
+		// 提取图像 URL
+		imageURLs := make([]string, 0, len(matches))
+		for _, match := range matches {
+			if len(match) > 1 {
+				imageURLs = append(imageURLs, match[1])
+			}
+		}
+
+		var thumbnailUrl, directThumbnailUrl string
+		if len(imageURLs) > 0 {
+			directThumbnailUrl = imageURLs[0]
+			thumbnailUrl = proxiedBilibiliImageUrl(options.ImageProxy, directThumbnailUrl)
+		} else if options.RequireThumbnail {
+			continue
+		}
+
+		if isBilibiliItemByBlockedAuthor(v, options.BlockAuthors) {
+			continue
+		}
+
+		duration := v.duration()
+		if options.MinDuration > 0 {
+			if duration == 0 && options.DropUnknownDuration || duration > 0 && duration < options.MinDuration {
+				continue
+			}
+		}
+
+		authorNames := make([]string, len(v.Authors))
+		var authorUrl, avatarUrl string
+		for i, author := range v.Authors {
+			authorNames[i] = author.Name
+
+			if alias, exists := options.AuthorAliases[author.Name]; exists {
+				authorNames[i] = alias
+			}
+
+			if authorUrl == "" && author.URL != "" {
+				authorUrl = author.URL
+			}
+
+			if avatarUrl == "" && author.Avatar != "" && options.AvatarUrlTemplate != "" {
+				avatarUrl = author.Avatar
+			}
+		}
+
+		if avatarUrl == "" {
+			avatarUrl = channelAvatarUrl
+		}
+
+		if avatarUrl != "" {
+			avatarUrl = proxiedBilibiliImageUrl(options.ImageProxy, avatarUrl)
+		}
+
+		if authorUrl == "" {
+			authorUrl = channelUrl
+		}
+
+		if authorUrl == "" {
+			authorUrl = response.HomePageURL
+		}
+
+		// unlike the author URL, this one never falls back to the video
+		sourceUrl := authorUrl
+
+		if authorUrl == "" {
+			authorUrl = v.URL
+		}
+
+		videos = append(videos, bilibiliVideo{
+			ThumbnailUrl:       thumbnailUrl,
+			DirectThumbnailUrl: directThumbnailUrl,
+			Title:              v.Title,
+			SourceLabel:        response.Title,
+			Url:                v.URL,
+			Author:             strings.Join(authorNames, ", "),
+			AuthorUrl:          authorUrl,
+			SourceUrl:          sourceUrl,
+			TimePosted:         v.DatePublished,
+			dateHasNoOffset:    v.dateHasNoOffset,
+			Duration:           duration,
+			Status:             v.status(),
+			AuthorAvatarUrl:    avatarUrl,
+			thumbnailPixels:    bilibiliThumbnailPixels(v.ContentHTML, directThumbnailUrl),
+			sequence:           bilibiliItemSequence(v.ID),
+		})
+
+		if options.HoverPreview && v.Preview != "" {
+			videos[len(videos)-1].PreviewUrl = proxiedBilibiliImageUrl(options.ImageProxy, v.Preview)
+		}
+
+		if len(options.ExtensionFields) > 0 {
+			videos[len(videos)-1].withExtensionFields(v.extensionFields(options.ExtensionFields), options.ExtensionFields)
+		}
+
+		if options.ValidateItems {
+			if err := videos[len(videos)-1].validate(); err != nil {
+				slog.Warn("Dropping invalid bilibili feed item", "rsshub url", feedUrl, "item", j, "reason", err)
+				videos = videos[:len(videos)-1]
+			}
+		}
+	}
+
+	return videos
+}
+
+// Images embedded in the page can't be fetched by the proxy and prefixing
+// them with it would only break them
+func proxiedBilibiliImageUrl(imageProxy string, imageUrl string) string {
+	if strings.HasPrefix(imageUrl, "data:") || strings.HasPrefix(imageUrl, "blob:") {
+		return imageUrl
+	}
+
+	return imageProxy + imageUrl
+}
+
+func isBilibiliItemByBlockedAuthor(item *bilibiliFeedItemJson, blockedAuthors []string) bool {
+	for _, author := range item.Authors {
+		for _, blocked := range blockedAuthors {
+			if strings.EqualFold(strings.TrimSpace(author.Name), strings.TrimSpace(blocked)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func mergeBilibiliFeedResults(results []bilibiliVideoList, errs []error, keepFeedOrder bool) (bilibiliVideoList, error) {
+	videos := make(bilibiliVideoList, 0, len(results)*15)
+	var failed int
+
+	for i := range results {
+		if errs[i] != nil {
+			failed++
+			continue
+		}
+
+		videos = append(videos, results[i]...)
+	}
+
+	if len(videos) == 0 {
+		return nil, errNoContent
+	}
+
+	if !keepFeedOrder {
+		videos.sortByNewest()
+	}
+
+	if failed > 0 {
+		return videos, fmt.Errorf("%w: missing videos from %d channels", errPartialContent, failed)
+	}
+
+	return videos, nil
+}
+
+// Fetches the feeds at the given indices, storing their videos and errors
+// at the same indices of results and errs
+func fetchBilibiliFeedsInto(options bilibiliFetchOptions, indices []int, results []bilibiliVideoList, errs []error) error {
+	fetchOptions := options
+	fetchOptions.FeedUrls = make([]string, len(indices))
+	for j, i := range indices {
+		fetchOptions.FeedUrls[j] = options.FeedUrls[i]
+	}
+
+	fetched, fetchErrs, err := fetchBilibiliFeeds(fetchOptions)
+	if err != nil {
+		return err
+	}
+
+	for j, i := range indices {
+		results[i], errs[i] = fetched[j], fetchErrs[j]
+	}
+
+	return nil
+}
+
+func areAllBilibiliFeedsEmpty(results []bilibiliVideoList, errs []error) bool {
+	succeeded := 0
+
+	for i := range results {
+		if errs[i] != nil {
+			continue
+		}
+
+		if len(results[i]) > 0 {
+			return false
+		}
+
+		succeeded++
+	}
+
+	return succeeded > 0
+}
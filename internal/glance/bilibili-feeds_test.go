@@ -0,0 +1,307 @@
+package glance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBilibiliThumbnailPixels(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		imageUrl string
+		want     int
+	}{
+		{"attributes", `<img src="https://i0.hdslb.com/a.jpg" width="640" height="360">`, "https://i0.hdslb.com/a.jpg", 640 * 360},
+		{"url suffix", `<img src="https://i0.hdslb.com/a.jpg@320w_180h.webp">`, "https://i0.hdslb.com/a.jpg@320w_180h.webp", 320 * 180},
+		{"unknown", `<img src="https://i0.hdslb.com/a.jpg">`, "https://i0.hdslb.com/a.jpg", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := bilibiliThumbnailPixels(test.content, test.imageUrl); got != test.want {
+				t.Errorf("expected %d, got %d", test.want, got)
+			}
+		})
+	}
+}
+
+func decodeTestBilibiliFeed(t *testing.T, feed string) *bilibiliFeedResponseJson {
+	t.Helper()
+
+	var response bilibiliFeedResponseJson
+	if err := json.Unmarshal([]byte(feed), &response); err != nil {
+		t.Fatalf("decoding feed: %v", err)
+	}
+
+	return &response
+}
+
+func TestBilibiliVideosFromFeedRequireThumbnail(t *testing.T) {
+	feed := decodeTestBilibiliFeed(t, `{"items": [
+		{"url": "https://www.bilibili.com/video/BV1", "title": "With thumbnail", "content_html": "<img src=\"https://i0.hdslb.com/a.jpg\">", "date_published": "2024-05-10T12:00:00Z"},
+		{"url": "https://www.bilibili.com/video/BV2", "title": "Without thumbnail", "content_html": "<p>text only</p>", "date_published": "2024-05-10T11:00:00Z"}
+	]}`)
+
+	tests := []struct {
+		name    string
+		require bool
+		want    []string
+	}{
+		{"not required", false, []string{"https://www.bilibili.com/video/BV1", "https://www.bilibili.com/video/BV2"}},
+		{"required", true, []string{"https://www.bilibili.com/video/BV1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := bilibiliFetchOptions{RequireThumbnail: test.require}
+			videos := bilibiliVideosFromFeed(options, "https://rsshub.example/bilibili/user/video/1", feed)
+
+			if got := bilibiliVideoUrls(videos); !slices.Equal(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosFromFeedAuthorUrl(t *testing.T) {
+	const videoUrl = "https://www.bilibili.com/video/BV1"
+
+	tests := []struct {
+		name     string
+		feedUrl  string
+		homePage string
+		author   string
+		template string
+		want     string
+	}{
+		{
+			name:    "uid from the route",
+			feedUrl: "https://rsshub.example/bilibili/user/video/123",
+			want:    "https://space.bilibili.com/123",
+		},
+		{
+			name:     "uid from the home page",
+			feedUrl:  "https://rsshub.example/custom",
+			homePage: "https://space.bilibili.com/456/video",
+			want:     "https://space.bilibili.com/456",
+		},
+		{
+			name:     "custom template",
+			feedUrl:  "https://rsshub.example/bilibili/user/video/123",
+			template: "https://m.bilibili.com/space/{uid}",
+			want:     "https://m.bilibili.com/space/123",
+		},
+		{
+			name:    "url of the author",
+			feedUrl: "https://rsshub.example/bilibili/user/video/123",
+			author:  "https://example.com/author",
+			want:    "https://example.com/author",
+		},
+		{
+			name:     "home page that isn't a channel",
+			feedUrl:  "https://rsshub.example/custom",
+			homePage: "https://example.com/",
+			want:     "https://example.com/",
+		},
+		{
+			// nothing to link to other than the video itself
+			name:    "not derivable",
+			feedUrl: "https://rsshub.example/custom",
+			want:    videoUrl,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			feed := decodeTestBilibiliFeed(t, fmt.Sprintf(`{"home_page_url": %q, "items": [
+				{"url": %q, "title": "Video", "date_published": "2024-05-10T12:00:00Z", "authors": [{"name": "Author", "url": %q}]}
+			]}`, test.homePage, videoUrl, test.author))
+
+			template := test.template
+			if template == "" {
+				template = "https://space.bilibili.com/{UID}"
+			}
+
+			videos := bilibiliVideosFromFeed(bilibiliFetchOptions{AuthorUrlTemplate: template}, test.feedUrl, feed)
+			if len(videos) != 1 {
+				t.Fatalf("expected 1 video, got %d", len(videos))
+			}
+
+			if videos[0].AuthorUrl != test.want {
+				t.Errorf("expected %q, got %q", test.want, videos[0].AuthorUrl)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosFromFeedBlockAuthors(t *testing.T) {
+	feed := decodeTestBilibiliFeed(t, `{"items": [
+		{"url": "https://www.bilibili.com/video/BV1", "title": "Solo", "date_published": "2024-05-10T12:00:00Z", "authors": [{"name": "Main"}]},
+		{"url": "https://www.bilibili.com/video/BV2", "title": "Collab", "date_published": "2024-05-10T11:00:00Z", "authors": [{"name": "Main"}, {"name": "Collaborator"}]},
+		{"url": "https://www.bilibili.com/video/BV3", "title": "Other", "date_published": "2024-05-10T10:00:00Z", "authors": [{"name": " collaborator "}]}
+	]}`)
+
+	tests := []struct {
+		name    string
+		blocked []string
+		want    []string
+	}{
+		{"none", nil, []string{"https://www.bilibili.com/video/BV1", "https://www.bilibili.com/video/BV2", "https://www.bilibili.com/video/BV3"}},
+		// case and surrounding whitespace don't matter
+		{"co-author", []string{"COLLABORATOR"}, []string{"https://www.bilibili.com/video/BV1"}},
+		{"unknown", []string{"Someone"}, []string{"https://www.bilibili.com/video/BV1", "https://www.bilibili.com/video/BV2", "https://www.bilibili.com/video/BV3"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := bilibiliFetchOptions{BlockAuthors: test.blocked}
+			videos := bilibiliVideosFromFeed(options, "https://rsshub.example/bilibili/user/video/1", feed)
+
+			if got := bilibiliVideoUrls(videos); !slices.Equal(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosFromFeedImageProxySkipsInlineImages(t *testing.T) {
+	feed := decodeTestBilibiliFeed(t, `{"items": [
+		{"url": "https://www.bilibili.com/video/BV1", "title": "Remote", "content_html": "<img src=\"https://i0.hdslb.com/a.jpg\">", "date_published": "2024-05-10T12:00:00Z"},
+		{"url": "https://www.bilibili.com/video/BV2", "title": "Inline", "content_html": "<img src=\"data:image/png;base64,iVBORw0KGgo=\">", "date_published": "2024-05-10T11:00:00Z"}
+	]}`)
+
+	options := bilibiliFetchOptions{ImageProxy: "https://proxy.example/?url="}
+	videos := bilibiliVideosFromFeed(options, "https://rsshub.example/bilibili/user/video/1", feed)
+
+	if len(videos) != 2 {
+		t.Fatalf("expected 2 videos, got %d", len(videos))
+	}
+
+	if want := "https://proxy.example/?url=https://i0.hdslb.com/a.jpg"; videos[0].ThumbnailUrl != want {
+		t.Errorf("expected the remote thumbnail to be proxied as %s, got %s", want, videos[0].ThumbnailUrl)
+	}
+
+	if want := "data:image/png;base64,iVBORw0KGgo="; videos[1].ThumbnailUrl != want {
+		t.Errorf("expected the inline thumbnail to be left as is, got %s", videos[1].ThumbnailUrl)
+	}
+}
+
+func TestProxiedBilibiliImageUrl(t *testing.T) {
+	tests := []struct {
+		imageUrl string
+		want     string
+	}{
+		{"https://i0.hdslb.com/a.jpg", "https://proxy.example/https://i0.hdslb.com/a.jpg"},
+		{"data:image/png;base64,iVBORw0KGgo=", "data:image/png;base64,iVBORw0KGgo="},
+		{"blob:https://www.bilibili.com/0c2b4a1e", "blob:https://www.bilibili.com/0c2b4a1e"},
+	}
+
+	for _, test := range tests {
+		if got := proxiedBilibiliImageUrl("https://proxy.example/", test.imageUrl); got != test.want {
+			t.Errorf("expected %s to become %s, got %s", test.imageUrl, test.want, got)
+		}
+	}
+}
+
+func TestBilibiliVideosFromFeedValidateItems(t *testing.T) {
+	feed := decodeTestBilibiliFeed(t, `{"items": [
+		{"url": "https://www.bilibili.com/video/BV1", "title": "Valid", "date_published": "2024-05-10T12:00:00Z"},
+		{"url": "https://www.bilibili.com/video/BV2", "title": "  ", "date_published": "2024-05-10T11:00:00Z"},
+		{"url": "/video/BV3", "title": "Relative", "date_published": "2024-05-10T10:00:00Z"},
+		{"url": "https://www.bilibili.com/video/BV4", "title": "Undated"}
+	]}`)
+
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	options := bilibiliFetchOptions{ValidateItems: true}
+	videos := bilibiliVideosFromFeed(options, "https://rsshub.example/bilibili/user/video/1", feed)
+
+	if want := []string{"https://www.bilibili.com/video/BV1"}; !slices.Equal(bilibiliVideoUrls(videos), want) {
+		t.Errorf("expected only the valid item to be kept, got %v", bilibiliVideoUrls(videos))
+	}
+
+	for _, reason := range []string{`reason="missing title"`, `is not absolute`, `reason="missing date"`} {
+		if !strings.Contains(logs.String(), reason) {
+			t.Errorf("expected the logs to contain %s, got:\n%s", reason, logs.String())
+		}
+	}
+
+	if unvalidated := bilibiliVideosFromFeed(bilibiliFetchOptions{}, "https://rsshub.example/bilibili/user/video/1", feed); len(unvalidated) != 4 {
+		t.Errorf("expected every item to be kept without validation, got %d", len(unvalidated))
+	}
+}
+
+func TestBilibiliVideosFromFeedAvatarUrl(t *testing.T) {
+	tests := []struct {
+		name     string
+		feedUrl  string
+		homePage string
+		avatar   string
+		template string
+		want     string
+	}{
+		{
+			name:     "uid from the route",
+			feedUrl:  "https://rsshub.example/bilibili/user/video/123",
+			template: "https://avatars.example/{uid}.jpg",
+			want:     "https://proxy.example/?url=https://avatars.example/123.jpg",
+		},
+		{
+			name:     "uid from the home page",
+			feedUrl:  "https://rsshub.example/custom",
+			homePage: "https://space.bilibili.com/456/video",
+			template: "https://avatars.example/{UID}.jpg",
+			want:     "https://proxy.example/?url=https://avatars.example/456.jpg",
+		},
+		{
+			name:     "avatar of the author",
+			feedUrl:  "https://rsshub.example/bilibili/user/video/123",
+			avatar:   "https://i0.hdslb.com/face.jpg",
+			template: "https://avatars.example/{uid}.jpg",
+			want:     "https://proxy.example/?url=https://i0.hdslb.com/face.jpg",
+		},
+		{
+			name:     "unknown uid",
+			feedUrl:  "https://rsshub.example/custom",
+			template: "https://avatars.example/{uid}.jpg",
+			want:     "",
+		},
+		{
+			name:    "no template",
+			feedUrl: "https://rsshub.example/bilibili/user/video/123",
+			avatar:  "https://i0.hdslb.com/face.jpg",
+			want:    "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			feed := decodeTestBilibiliFeed(t, fmt.Sprintf(`{"home_page_url": %q, "items": [
+				{"url": "https://www.bilibili.com/video/BV1", "title": "Video", "date_published": "2024-05-10T12:00:00Z", "authors": [{"name": "Author", "avatar": %q}]}
+			]}`, test.homePage, test.avatar))
+
+			options := bilibiliFetchOptions{
+				AvatarUrlTemplate: test.template,
+				ImageProxy:        "https://proxy.example/?url=",
+			}
+
+			videos := bilibiliVideosFromFeed(options, test.feedUrl, feed)
+			if len(videos) != 1 {
+				t.Fatalf("expected 1 video, got %d", len(videos))
+			}
+
+			if videos[0].AuthorAvatarUrl != test.want {
+				t.Errorf("expected %q, got %q", test.want, videos[0].AuthorAvatarUrl)
+			}
+		})
+	}
+}
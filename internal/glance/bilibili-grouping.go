@@ -0,0 +1,137 @@
+package glance
+
+import "time"
+
+type bilibiliVideoGroup struct {
+	Title  string
+	Url    string
+	Videos bilibiliVideoList
+}
+
+// Groups videos by author, ordering the groups by each author's first
+// appearance in the list, so a sorted list yields groups sorted the same way
+func (v bilibiliVideoList) groupByAuthor() []bilibiliVideoGroup {
+	groups := make([]bilibiliVideoGroup, 0)
+	indexByAuthor := make(map[string]int)
+
+	for i := range v {
+		index, exists := indexByAuthor[v[i].Author]
+
+		if !exists {
+			index = len(groups)
+			indexByAuthor[v[i].Author] = index
+			groups = append(groups, bilibiliVideoGroup{
+				Title: v[i].Author,
+				Url:   v[i].SourceUrl,
+			})
+		}
+
+		groups[index].Videos = append(groups[index].Videos, v[i])
+	}
+
+	return groups
+}
+
+// Groups consecutive videos by the day or ISO week they were posted in,
+// relative to the given time and in its location
+func (v bilibiliVideoList) groupByPeriod(period string, now time.Time) []bilibiliVideoGroup {
+	groups := make([]bilibiliVideoGroup, 0)
+	currentStart := periodStart(period, now)
+	var lastStart time.Time
+
+	for i := range v {
+		start := periodStart(period, v[i].TimePosted.In(now.Location()))
+
+		if len(groups) == 0 || !start.Equal(lastStart) {
+			lastStart = start
+			groups = append(groups, bilibiliVideoGroup{
+				Title: periodLabel(period, start, currentStart),
+			})
+		}
+
+		groups[len(groups)-1].Videos = append(groups[len(groups)-1].Videos, v[i])
+	}
+
+	withCommonSourceUrls(groups)
+
+	return groups
+}
+
+// Groups videos by the feed they came from, ordering the groups the same way
+// the feeds are configured and leaving out feeds without any videos
+func (v bilibiliVideoList) groupByFeed(feedCount int) []bilibiliVideoGroup {
+	byFeed := make([]bilibiliVideoGroup, feedCount)
+
+	for i := range v {
+		group := &byFeed[v[i].feedIndex]
+
+		if len(group.Videos) == 0 {
+			group.Title = v[i].SourceLabel
+			if group.Title == "" {
+				group.Title = v[i].Author
+			}
+		}
+
+		group.Videos = append(group.Videos, v[i])
+	}
+
+	groups := make([]bilibiliVideoGroup, 0, feedCount)
+	for i := range byFeed {
+		if len(byFeed[i].Videos) > 0 {
+			groups = append(groups, byFeed[i])
+		}
+	}
+
+	withCommonSourceUrls(groups)
+
+	return groups
+}
+
+// Headers can only link somewhere when everything in them came from the same source
+func withCommonSourceUrls(groups []bilibiliVideoGroup) {
+	for i := range groups {
+		groups[i].Url = groups[i].Videos[0].SourceUrl
+
+		for j := range groups[i].Videos {
+			if groups[i].Videos[j].SourceUrl != groups[i].Url {
+				groups[i].Url = ""
+				break
+			}
+		}
+	}
+}
+
+// Returns the midnight at the start of the day or ISO week (which starts on
+// Monday) that the given time falls in
+func periodStart(period string, t time.Time) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	if period == "week" {
+		daysSinceMonday := (int(start.Weekday()) + 6) % 7
+		start = start.AddDate(0, 0, -daysSinceMonday)
+	}
+
+	return start
+}
+
+func periodLabel(period string, start time.Time, currentStart time.Time) string {
+	if period == "week" {
+		switch {
+		case start.Equal(currentStart):
+			return "This week"
+		case start.Equal(currentStart.AddDate(0, 0, -7)):
+			return "Last week"
+		}
+
+		return "Week of " + start.Format("Jan 2")
+	}
+
+	switch {
+	case start.Equal(currentStart):
+		return "Today"
+	case start.Equal(currentStart.AddDate(0, 0, -1)):
+		return "Yesterday"
+	}
+
+	return start.Format("Mon, Jan 2")
+}
@@ -0,0 +1,192 @@
+package glance
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bilibiliTestVideoGroup struct {
+	title string
+	url   string
+	urls  []string
+}
+
+func assertBilibiliVideoGroups(t *testing.T, groups []bilibiliVideoGroup, want []bilibiliTestVideoGroup) {
+	t.Helper()
+
+	if len(groups) != len(want) {
+		t.Fatalf("expected %d groups, got %d: %+v", len(want), len(groups), groups)
+	}
+
+	for i := range want {
+		if groups[i].Title != want[i].title || groups[i].Url != want[i].url {
+			t.Errorf("group %d: expected %q linking to %q, got %q linking to %q", i, want[i].title, want[i].url, groups[i].Title, groups[i].Url)
+		}
+
+		if urls := bilibiliVideoUrls(groups[i].Videos); !slices.Equal(urls, want[i].urls) {
+			t.Errorf("group %d: expected %v, got %v", i, want[i].urls, urls)
+		}
+	}
+}
+
+// Two feeds with two videos each, posted an hour, two hours, a day and
+// five days before the given time
+func bilibiliGroupingTestVideos(now time.Time) bilibiliVideoList {
+	return bilibiliVideoList{
+		{Url: "a1", Author: "A", SourceUrl: "feed-a", SourceLabel: "Feed A", feedIndex: 0, TimePosted: now.Add(-time.Hour)},
+		{Url: "b1", Author: "B", SourceUrl: "feed-b", feedIndex: 1, TimePosted: now.Add(-2 * time.Hour)},
+		{Url: "a2", Author: "A", SourceUrl: "feed-a", SourceLabel: "Feed A", feedIndex: 0, TimePosted: now.Add(-26 * time.Hour)},
+		{Url: "b2", Author: "B", SourceUrl: "feed-b", feedIndex: 1, TimePosted: now.Add(-5 * 24 * time.Hour)},
+	}
+}
+
+func TestBilibiliVideoListGroupByAuthor(t *testing.T) {
+	videos := bilibiliGroupingTestVideos(time.Now())
+
+	assertBilibiliVideoGroups(t, videos.groupByAuthor(), []bilibiliTestVideoGroup{
+		{title: "A", url: "feed-a", urls: []string{"a1", "a2"}},
+		{title: "B", url: "feed-b", urls: []string{"b1", "b2"}},
+	})
+}
+
+func TestBilibiliVideoListGroupByPeriod(t *testing.T) {
+	// a friday
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	videos := bilibiliGroupingTestVideos(now)
+
+	tests := []struct {
+		period string
+		want   []bilibiliTestVideoGroup
+	}{
+		{
+			period: "day",
+			want: []bilibiliTestVideoGroup{
+				// videos from different feeds can't link to either of them
+				{title: "Today", urls: []string{"a1", "b1"}},
+				{title: "Yesterday", url: "feed-a", urls: []string{"a2"}},
+				{title: "Sun, May 5", url: "feed-b", urls: []string{"b2"}},
+			},
+		},
+		{
+			period: "week",
+			want: []bilibiliTestVideoGroup{
+				{title: "This week", urls: []string{"a1", "b1", "a2"}},
+				{title: "Last week", url: "feed-b", urls: []string{"b2"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.period, func(t *testing.T) {
+			assertBilibiliVideoGroups(t, videos.groupByPeriod(test.period, now), test.want)
+		})
+	}
+}
+
+func TestBilibiliVideoListGroupByFeed(t *testing.T) {
+	videos := bilibiliGroupingTestVideos(time.Now())
+
+	// the third feed has no videos and gets left out, feeds without a label
+	// are titled after the author
+	assertBilibiliVideoGroups(t, videos.groupByFeed(3), []bilibiliTestVideoGroup{
+		{title: "Feed A", url: "feed-a", urls: []string{"a1", "a2"}},
+		{title: "B", url: "feed-b", urls: []string{"b1", "b2"}},
+	})
+}
+
+func TestBilibiliVideosAuthorCarouselRender(t *testing.T) {
+	widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\ngroup: author-carousel\n")
+	widget.ContentAvailable = true
+	widget.Videos = bilibiliGroupingTestVideos(time.Now())
+	widget.Groups = widget.Videos.groupByAuthor()
+
+	rendered := string(widget.Render())
+
+	// everything before the first carousel is left out
+	carousels := strings.Split(rendered, `<div class="carousel-container">`)[1:]
+	if len(carousels) != 2 {
+		t.Fatalf("expected a carousel for each of the 2 authors, got %d:\n%s", len(carousels), rendered)
+	}
+
+	want := [][]string{{"a1", "a2"}, {"b1", "b2"}}
+	for i, carousel := range carousels {
+		for _, url := range []string{"a1", "a2", "b1", "b2"} {
+			shown := strings.Contains(carousel, `href="`+url+`"`)
+
+			if shown != slices.Contains(want[i], url) {
+				t.Errorf("carousel %d: expected %s to be shown: %v", i, url, !shown)
+			}
+		}
+	}
+}
+
+func TestBilibiliVideosGroupHeaderLinks(t *testing.T) {
+	now := time.Now()
+	videos := bilibiliVideoList{
+		{Url: "https://www.bilibili.com/video/BV1", Title: "A1", Author: "A", SourceUrl: "https://space.bilibili.com/1", TimePosted: now},
+		{Url: "https://www.bilibili.com/video/BV2", Title: "A2", Author: "A", SourceUrl: "https://space.bilibili.com/1", TimePosted: now.Add(-time.Minute)},
+		{Url: "https://www.bilibili.com/video/BV3", Title: "B1", Author: "B", TimePosted: now.Add(-2 * time.Minute)},
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		group  func(widget *bilibiliVideosWidget)
+		linked []string
+		plain  []string
+	}{
+		{
+			name:   "author carousel",
+			config: "group: author-carousel\n",
+			group:  func(widget *bilibiliVideosWidget) { widget.Groups = widget.Videos.groupByAuthor() },
+			linked: []string{"A"},
+			plain:  []string{"B"},
+		},
+		{
+			// the day has videos from different sources, so there's nothing to link to
+			name:   "group by day",
+			config: "style: vertical-list\ngroup-by: day\n",
+			group:  func(widget *bilibiliVideosWidget) { widget.PeriodGroups = widget.Videos.groupByPeriod("day", now) },
+			plain:  []string{"Today"},
+		},
+		{
+			name:   "group by day from a single source",
+			config: "style: vertical-list\ngroup-by: day\n",
+			group: func(widget *bilibiliVideosWidget) {
+				widget.PeriodGroups = widget.Videos[:2].groupByPeriod("day", now)
+			},
+			linked: []string{"Today"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\n"+test.config)
+			widget.ContentAvailable = true
+			widget.Videos = slices.Clone(videos)
+			test.group(widget)
+
+			rendered := string(widget.Render())
+
+			for _, title := range test.linked {
+				if want := `<a href="https://space.bilibili.com/1" target="_blank" rel="noreferrer">` + title + `</a>`; !strings.Contains(rendered, want) {
+					t.Errorf("expected the header %s to link to its source, got:\n%s", title, rendered)
+				}
+			}
+
+			for _, title := range test.plain {
+				// unlike the links of the cards, those of headers have no class
+				if regexp.MustCompile(`<a href="[^"]*" target="_blank" rel="noreferrer">` + title + `</a>`).MatchString(rendered) {
+					t.Errorf("expected the header %s not to be a link", title)
+				}
+
+				if !strings.Contains(rendered, title) {
+					t.Errorf("expected the header %s to be shown", title)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package glance
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+// Fetches the following pages of the given feeds, all of them at once for each
+// page, until there are enough videos to reach the limit or the feeds run out
+// of pages. Failing to fetch a page only stops its feed from paginating further
+func (widget *bilibiliVideosWidget) fetchFurtherPages(options bilibiliFetchOptions, indices []int, results []bilibiliVideoList, errs []error) {
+	paginating := make([]int, 0, len(indices))
+	for _, i := range indices {
+		if errs[i] == nil && len(results[i]) > 0 {
+			paginating = append(paginating, i)
+		}
+	}
+
+	for page := 2; page <= widget.Pages && len(paginating) > 0; page++ {
+		total := 0
+		for i := range results {
+			if errs[i] == nil {
+				total += len(results[i])
+			}
+		}
+
+		if total >= widget.Limit {
+			return
+		}
+
+		pageOptions := options
+		pageOptions.FeedUrls = make([]string, len(paginating))
+		for j, i := range paginating {
+			pageOptions.FeedUrls[j] = bilibiliFeedPageUrl(options.FeedUrls[i], page)
+		}
+
+		fetched, fetchErrs, err := fetchBilibiliFeeds(pageOptions)
+		if err != nil {
+			return
+		}
+
+		stillPaginating := paginating[:0]
+
+		for j, i := range paginating {
+			if fetchErrs[j] != nil {
+				continue
+			}
+
+			widget.RSSHubUrls[i].transform(fetched[j])
+
+			// new uploads push videos onto the next page, which would otherwise
+			// end up being shown twice
+			added := 0
+			for _, video := range fetched[j] {
+				if !slices.ContainsFunc(results[i], func(v bilibiliVideo) bool { return v.Url == video.Url }) {
+					results[i] = append(results[i], video)
+					added++
+				}
+			}
+
+			if added > 0 {
+				stillPaginating = append(stillPaginating, i)
+			}
+		}
+
+		paginating = stillPaginating
+	}
+}
+
+func bilibiliFeedPageUrl(feedUrl string, page int) string {
+	parsed, err := url.Parse(feedUrl)
+	if err != nil {
+		return feedUrl
+	}
+
+	query := parsed.Query()
+	query.Set("page", strconv.Itoa(page))
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
@@ -0,0 +1,66 @@
+package glance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBilibiliVideosFetchFurtherPages(t *testing.T) {
+	now := time.Now()
+	// the third video shows up on both the first and second page, as
+	// happens when something gets uploaded between the two requests
+	pages := map[string][]int{
+		"":  {1, 2, 3},
+		"2": {3, 4, 5},
+		"3": {6, 7, 8},
+		"4": {9, 10, 11},
+	}
+
+	var mu sync.Mutex
+	var requested []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+
+		mu.Lock()
+		requested = append(requested, page)
+		mu.Unlock()
+
+		if r.URL.Query().Get("key") != "secret" {
+			http.Error(w, "missing key", http.StatusForbidden)
+			return
+		}
+
+		items := make([]string, 0, len(pages[page]))
+		for _, n := range pages[page] {
+			items = append(items, bilibiliTestItem(fmt.Sprintf("BV%d", n), fmt.Sprintf("Video %d", n), now.Add(-time.Duration(n)*time.Minute)))
+		}
+
+		w.Write([]byte(bilibiliTestFeed(items...)))
+	}))
+	defer server.Close()
+
+	widget := newTestBilibiliVideosWidget(t, "limit: 7\npages: 5\nrsshuburls:\n  - "+server.URL+"/feed?key=secret\n")
+	widget.update(context.Background())
+
+	// after the third page there are enough videos for the limit
+	if want := []string{"", "2", "3"}; !slices.Equal(requested, want) {
+		t.Errorf("expected the pages %q to be requested, got %q", want, requested)
+	}
+
+	want := make([]string, 0, 7)
+	for n := 1; n <= 7; n++ {
+		want = append(want, "https://www.bilibili.com/video/BV"+strconv.Itoa(n))
+	}
+
+	if got := bilibiliVideoUrls(widget.Videos); !slices.Equal(got, want) {
+		t.Errorf("expected the videos %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,196 @@
+package glance
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func (v bilibiliVideoList) withThumbnailColors() {
+	for i := range v {
+		// the proxy may not be reachable from the server
+		thumbnailUrl := v[i].DirectThumbnailUrl
+		if thumbnailUrl == "" {
+			thumbnailUrl = v[i].ThumbnailUrl
+		}
+
+		if thumbnailUrl == "" {
+			continue
+		}
+
+		v[i].ThumbnailColor, _ = cachedThumbnailColor(thumbnailUrl)
+	}
+}
+
+// Sets the icon of each video to the favicon of the site it links to,
+// using the platform icon for sites whose favicon could not be resolved
+func (v bilibiliVideoList) withSourceIcons(platformIconUrl string) {
+	pageUrls := make([]string, len(v))
+	for i := range v {
+		pageUrls[i] = v[i].Url
+	}
+
+	faviconUrls := resolveFaviconURLs(pageUrls)
+
+	for i := range v {
+		if i < len(faviconUrls) && faviconUrls[i] != "" {
+			v[i].SourceIconUrl = faviconUrls[i]
+			continue
+		}
+
+		v[i].SourceIconUrl = platformIconUrl
+		v[i].SourceIconIsFlat = true
+	}
+}
+
+var bilibiliPlatformIcons = map[string]string{
+	"bilibili.com": "icons/bilibili.svg",
+	"b23.tv":       "icons/bilibili.svg",
+}
+
+// Returns the path of the icon of the platform the page is hosted on, or an
+// empty string if it isn't on a known platform
+func bilibiliPlatformIcon(pageUrl string) string {
+	parsedUrl, err := url.Parse(pageUrl)
+	if err != nil {
+		return ""
+	}
+
+	host := parsedUrl.Hostname()
+
+	for domain, icon := range bilibiliPlatformIcons {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return icon
+		}
+	}
+
+	return ""
+}
+
+func (v bilibiliVideoList) withPlatformIcons(useFavicons bool, resolveAsset func(string) string) {
+	unknown := make([]int, 0)
+
+	for i := range v {
+		if icon := bilibiliPlatformIcon(v[i].Url); icon != "" {
+			v[i].SourceIconUrl = resolveAsset(icon)
+			v[i].SourceIconIsFlat = true
+			continue
+		}
+
+		unknown = append(unknown, i)
+	}
+
+	var faviconUrls []string
+	if useFavicons && len(unknown) > 0 {
+		pageUrls := make([]string, len(unknown))
+		for j, i := range unknown {
+			pageUrls[j] = v[i].Url
+		}
+
+		faviconUrls = resolveFaviconURLs(pageUrls)
+	}
+
+	for j, i := range unknown {
+		if j < len(faviconUrls) && faviconUrls[j] != "" {
+			v[i].SourceIconUrl = faviconUrls[j]
+			continue
+		}
+
+		v[i].SourceIconUrl = resolveAsset("icons/link.svg")
+		v[i].SourceIconIsFlat = true
+	}
+}
+
+// Makes the browser swap to the direct thumbnail URL if the proxied one
+// fails to load or doesn't load within the given timeout
+func (v bilibiliVideoList) withThumbnailFallback(timeout time.Duration) {
+	for i := range v {
+		if v[i].DirectThumbnailUrl == "" || v[i].DirectThumbnailUrl == v[i].ThumbnailUrl {
+			continue
+		}
+
+		v[i].FallbackThumbnailUrl = v[i].DirectThumbnailUrl
+		v[i].FallbackTimeoutMs = timeout.Milliseconds()
+	}
+}
+
+// Sets the image the browser swaps to as a last resort when neither the
+// thumbnail nor its fallback can be loaded
+func (v bilibiliVideoList) withDefaultThumbnails(defaults map[string]string) {
+	for i := range v {
+		if v[i].ThumbnailUrl == "" {
+			continue
+		}
+
+		v[i].DefaultThumbnailUrl = defaultThumbnailForPlatform(v[i].Url, defaults)
+	}
+}
+
+// The most specific domain wins when several of them match, e.g. one set for
+// live.bilibili.com over one set for bilibili.com
+func defaultThumbnailForPlatform(pageUrl string, defaults map[string]string) string {
+	parsedUrl, err := url.Parse(pageUrl)
+	if err != nil {
+		return defaults["*"]
+	}
+
+	host := strings.TrimPrefix(parsedUrl.Hostname(), "www.")
+	matched := ""
+
+	for domain := range defaults {
+		if len(domain) <= len(matched) {
+			continue
+		}
+
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			matched = domain
+		}
+	}
+
+	if matched == "" {
+		return defaults["*"]
+	}
+
+	return defaults[matched]
+}
+
+// Spreads proxied thumbnails across the given proxies, the shard is picked
+// based on the image so that it always gets loaded from the same one and
+// stays cached in the browser between updates
+func (v bilibiliVideoList) withShardedImageProxy(imageProxy string, shards []string) {
+	for i := range v {
+		if v[i].DirectThumbnailUrl == "" || !strings.HasPrefix(v[i].ThumbnailUrl, imageProxy) {
+			continue
+		}
+
+		hash := fnv.New32a()
+		hash.Write([]byte(v[i].DirectThumbnailUrl))
+		shard := shards[hash.Sum32()%uint32(len(shards))]
+
+		v[i].ThumbnailUrl = shard + strings.TrimPrefix(v[i].ThumbnailUrl, imageProxy)
+	}
+}
+
+// Only thumbnails that go through the image proxy can be resized
+func (v bilibiliVideoList) withThumbnailSrcset(widths []int, param string, sizes string) {
+	for i := range v {
+		if v[i].DirectThumbnailUrl == "" || v[i].DirectThumbnailUrl == v[i].ThumbnailUrl {
+			continue
+		}
+
+		separator := "?"
+		if strings.Contains(v[i].ThumbnailUrl, "?") {
+			separator = "&"
+		}
+
+		candidates := make([]string, len(widths))
+		for j, width := range widths {
+			candidates[j] = fmt.Sprintf("%s%s%s=%d %dw", v[i].ThumbnailUrl, separator, param, width, width)
+		}
+
+		v[i].ThumbnailSrcset = strings.Join(candidates, ", ")
+		v[i].ThumbnailSizes = sizes
+	}
+}
@@ -0,0 +1,39 @@
+package glance
+
+import "testing"
+
+func TestDefaultThumbnailForPlatform(t *testing.T) {
+	defaults := map[string]string{
+		"*":                   "/fallback.png",
+		"bilibili.com":        "/bilibili.png",
+		"live.bilibili.com":   "/live.png",
+		"m.live.bilibili.com": "/mobile-live.png",
+		"youtube.com":         "/youtube.png",
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.bilibili.com/video/BV1", "/bilibili.png"},
+		{"https://space.bilibili.com/1", "/bilibili.png"},
+		{"https://live.bilibili.com/1", "/live.png"},
+		{"https://m.live.bilibili.com/1", "/mobile-live.png"},
+		{"https://youtube.com/watch?v=1", "/youtube.png"},
+		// only whole labels of the domain match
+		{"https://notbilibili.com/1", "/fallback.png"},
+		{"https://example.com/1", "/fallback.png"},
+		{"://invalid", "/fallback.png"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			// map iteration order varies between runs, which shouldn't affect the pick
+			for range 20 {
+				if got := defaultThumbnailForPlatform(test.url, defaults); got != test.want {
+					t.Fatalf("expected %q, got %q", test.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package glance
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Only freshly fetched videos get transformed, the cached ones already were
+func (widget *bilibiliVideosWidget) transformFetchedFeeds(indices []int, results []bilibiliVideoList, errs []error) {
+	for _, i := range indices {
+		if errs[i] != nil {
+			continue
+		}
+
+		widget.RSSHubUrls[i].transform(results[i])
+	}
+}
+
+func (f *bilibiliFeedField) transform(videos bilibiliVideoList) {
+	if f.sourceLocation != nil {
+		videos.withSourceLocation(f.sourceLocation)
+	}
+
+	if len(f.Transforms) > 0 {
+		videos.withTransforms(f.Transforms)
+	}
+}
+
+type bilibiliVideoTransform struct {
+	// one of strip-prefix, replace, trim or map
+	Op    string `yaml:"op"`
+	Field string `yaml:"field"`
+	// the prefix for strip-prefix
+	Value   string            `yaml:"value"`
+	Pattern string            `yaml:"pattern"`
+	With    string            `yaml:"with"`
+	Values  map[string]string `yaml:"values"`
+
+	pattern *regexp.Regexp
+}
+
+func (t *bilibiliVideoTransform) UnmarshalYAML(node *yaml.Node) error {
+	type bilibiliVideoTransformAlias bilibiliVideoTransform
+
+	if err := node.Decode((*bilibiliVideoTransformAlias)(t)); err != nil {
+		return err
+	}
+
+	switch t.Field {
+	case "":
+		t.Field = "title"
+	case "title", "author":
+	default:
+		return fmt.Errorf("line %d: transform field must be either title or author", node.Line)
+	}
+
+	switch t.Op {
+	case "strip-prefix":
+		if t.Value == "" {
+			return fmt.Errorf("line %d: strip-prefix requires a value", node.Line)
+		}
+	case "replace":
+		pattern, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid replace pattern: %v", node.Line, err)
+		}
+
+		t.pattern = pattern
+	case "trim":
+	case "map":
+		if len(t.Values) == 0 {
+			return fmt.Errorf("line %d: map requires values", node.Line)
+		}
+	default:
+		return fmt.Errorf("line %d: transform op must be one of strip-prefix, replace, trim or map", node.Line)
+	}
+
+	return nil
+}
+
+func (t *bilibiliVideoTransform) apply(value string) string {
+	switch t.Op {
+	case "strip-prefix":
+		return strings.TrimPrefix(value, t.Value)
+	case "replace":
+		return t.pattern.ReplaceAllString(value, t.With)
+	case "trim":
+		return strings.TrimSpace(value)
+	case "map":
+		if mapped, exists := t.Values[value]; exists {
+			return mapped
+		}
+	}
+
+	return value
+}
+
+func (v bilibiliVideoList) withTransforms(transforms []bilibiliVideoTransform) {
+	for i := range v {
+		for j := range transforms {
+			transform := &transforms[j]
+
+			if transform.Field == "author" {
+				v[i].Author = transform.apply(v[i].Author)
+			} else {
+				v[i].Title = transform.apply(v[i].Title)
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package glance
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestBilibiliVideosFeedTransforms(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/transformed": bilibiliTestFeed(bilibiliTestItem("BV1", "【Live】  Stream #12 recap", time.Now())),
+		"/untouched":   bilibiliTestFeed(bilibiliTestItem("BV2", "【Live】 Other #3", time.Now().Add(-time.Hour))),
+	})
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls:
+  - url: `+server.URL+`/transformed
+    transforms:
+      - op: strip-prefix
+        value: 【Live】
+      - op: trim
+      - op: replace
+        pattern: '#(\d+)'
+        with: Ep. $1
+      - op: map
+        field: author
+        values:
+          Author: Friendly Author
+  - `+server.URL+`/untouched
+`)
+	widget.update(context.Background())
+
+	if len(widget.Videos) != 2 {
+		t.Fatalf("expected 2 videos, got %d", len(widget.Videos))
+	}
+
+	want := []struct{ title, author string }{
+		{"Stream Ep. 12 recap", "Friendly Author"},
+		{"【Live】 Other #3", "Author"},
+	}
+
+	for i := range want {
+		if widget.Videos[i].Title != want[i].title || widget.Videos[i].Author != want[i].author {
+			t.Errorf("video %d: expected %q by %q, got %q by %q", i, want[i].title, want[i].author, widget.Videos[i].Title, widget.Videos[i].Author)
+		}
+	}
+}
+
+func TestBilibiliVideoTransformValidation(t *testing.T) {
+	tests := []struct {
+		config  string
+		wantErr string
+	}{
+		{"op: replace\npattern: '('", "invalid replace pattern"},
+		{"op: strip-prefix", "strip-prefix requires a value"},
+		{"op: map", "map requires values"},
+		{"op: upper", "transform op must be one of"},
+		{"op: trim\nfield: url", "transform field must be either title or author"},
+	}
+
+	for _, test := range tests {
+		var transform bilibiliVideoTransform
+		err := yaml.Unmarshal([]byte(test.config), &transform)
+
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("expected %q to fail with %q, got %v", test.config, test.wantErr, err)
+		}
+	}
+}
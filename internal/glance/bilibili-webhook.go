@@ -0,0 +1,64 @@
+package glance
+
+import "log/slog"
+
+type bilibiliUpdateWebhookPayload struct {
+	WidgetID  uint64                     `json:"widget-id"`
+	Type      string                     `json:"type"`
+	Title     string                     `json:"title"`
+	NewVideos int                        `json:"new-videos"`
+	Videos    []bilibiliWebhookVideoJson `json:"videos"`
+}
+
+type bilibiliWebhookVideoJson struct {
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Author string `json:"author"`
+}
+
+// Compares the shown videos with the ones shown after the previous update and
+// sends the ones that are new to the webhook. Nothing gets sent after the
+// first update since there's nothing to compare against yet
+func (widget *bilibiliVideosWidget) notifyAboutNewVideos() {
+	videos := widget.shownVideos()
+	previous := widget.previousVideoUrls
+
+	widget.previousVideoUrls = make(map[string]struct{}, len(videos))
+	for i := range videos {
+		widget.previousVideoUrls[videos[i].Url] = struct{}{}
+	}
+
+	if previous == nil {
+		return
+	}
+
+	payload := bilibiliUpdateWebhookPayload{
+		WidgetID: widget.ID,
+		Type:     widget.Type,
+		Title:    widget.Title,
+	}
+
+	for i := range videos {
+		if _, exists := previous[videos[i].Url]; exists {
+			continue
+		}
+
+		payload.Videos = append(payload.Videos, bilibiliWebhookVideoJson{
+			Title:  videos[i].Title,
+			URL:    videos[i].Url,
+			Author: videos[i].Author,
+		})
+	}
+
+	if len(payload.Videos) == 0 {
+		return
+	}
+
+	payload.NewVideos = len(payload.Videos)
+
+	go func() {
+		if err := sendWebhook(widget.OnUpdateWebhook, payload); err != nil {
+			slog.Error("Failed to send update webhook", "widget", payload.WidgetID, "error", err)
+		}
+	}()
+}
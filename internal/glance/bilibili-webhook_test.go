@@ -0,0 +1,94 @@
+package glance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBilibiliVideosOnUpdateWebhook(t *testing.T) {
+	calls := make(chan bilibiliUpdateWebhookPayload, 10)
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload bilibiliUpdateWebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+
+		calls <- payload
+	}))
+	defer webhook.Close()
+
+	now := time.Now()
+	feeds := map[string]string{
+		"/feed": bilibiliTestFeed(
+			bilibiliTestItem("BV1", "First", now.Add(-2*time.Hour)),
+		),
+	}
+	server := newTestBilibiliFeedServer(t, feeds)
+
+	widget := newTestBilibiliVideosWidget(t, `
+title: Videos
+rsshuburls: [`+server.URL+`/feed]
+on-update-webhook: `+webhook.URL+`
+`)
+
+	expectCalls := func(want int) []bilibiliUpdateWebhookPayload {
+		t.Helper()
+
+		received := make([]bilibiliUpdateWebhookPayload, 0, want)
+		for range want {
+			select {
+			case payload := <-calls:
+				received = append(received, payload)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("expected %d webhook calls, got %d", want, len(received))
+			}
+		}
+
+		select {
+		case payload := <-calls:
+			t.Fatalf("expected no more than %d webhook calls, got another one with %+v", want, payload)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		return received
+	}
+
+	// there's nothing to compare the first update against
+	widget.update(context.Background())
+	expectCalls(0)
+
+	widget.update(context.Background())
+	expectCalls(0)
+
+	feeds["/feed"] = bilibiliTestFeed(
+		bilibiliTestItem("BV3", "Third", now.Add(-10*time.Minute)),
+		bilibiliTestItem("BV2", "Second", now.Add(-1*time.Hour)),
+		bilibiliTestItem("BV1", "First", now.Add(-2*time.Hour)),
+	)
+
+	widget.update(context.Background())
+	payload := expectCalls(1)[0]
+
+	if payload.Title != "Videos" || payload.Type != "bilibili-videos" {
+		t.Errorf("expected the payload to describe the widget, got %+v", payload)
+	}
+
+	if payload.NewVideos != 2 {
+		t.Errorf("expected 2 new videos, got %d", payload.NewVideos)
+	}
+
+	urls := make([]string, len(payload.Videos))
+	for i := range payload.Videos {
+		urls[i] = payload.Videos[i].URL
+	}
+
+	if want := []string{"https://www.bilibili.com/video/BV3", "https://www.bilibili.com/video/BV2"}; !slices.Equal(urls, want) {
+		t.Errorf("expected the new videos %v, got %v", want, urls)
+	}
+}
@@ -0,0 +1,100 @@
+package glance
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+)
+
+// Converts response bodies in other charsets to UTF-8, going by their BOM
+// first and the charset of their Content-Type second. Bodies without either
+// are assumed to already be UTF-8 and get passed through as they are
+type charsetTranscodingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *charsetTranscodingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.base.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(response.Body)
+	decoder := detectBodyCharset(buffered, response.Header.Get("Content-Type"))
+	if decoder == nil {
+		response.Body = struct {
+			io.Reader
+			io.Closer
+		}{buffered, response.Body}
+
+		return response, nil
+	}
+
+	response.Body = struct {
+		io.Reader
+		io.Closer
+	}{transform.NewReader(buffered, decoder), response.Body}
+
+	// the length changes along with the encoding
+	response.ContentLength = -1
+	response.Header.Del("Content-Length")
+
+	return response, nil
+}
+
+// Returns nil when the body is already UTF-8, skipping its BOM if it has one
+func detectBodyCharset(body *bufio.Reader, contentType string) *encoding.Decoder {
+	start, _ := body.Peek(len(utf8BOM))
+
+	switch {
+	case bytes.HasPrefix(start, utf8BOM):
+		body.Discard(len(utf8BOM))
+		return nil
+	case bytes.HasPrefix(start, utf16BEBOM), bytes.HasPrefix(start, utf16LEBOM):
+		// the BOM says which of the two it is
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+	}
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["charset"] == "" {
+		return nil
+	}
+
+	charset, err := htmlindex.Get(params["charset"])
+	if err != nil {
+		slog.Warn("Unknown charset in response, assuming UTF-8", "charset", params["charset"])
+		return nil
+	}
+
+	if charset == unicode.UTF8 {
+		return nil
+	}
+
+	return charset.NewDecoder()
+}
+
+func withCharsetTranscoding(client *http.Client) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	transcoding := *client
+	transcoding.Transport = &charsetTranscodingTransport{base: base}
+
+	return &transcoding
+}
@@ -0,0 +1,95 @@
+package glance
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestCharsetTranscodingTransport(t *testing.T) {
+	tests := []struct {
+		name              string
+		contentType       string
+		body              []byte
+		want              string
+		wantLengthDropped bool
+	}{
+		{
+			name:        "utf-8 without charset",
+			contentType: "application/json",
+			body:        []byte(`{"title":"你好"}`),
+			want:        `{"title":"你好"}`,
+		},
+		{
+			name:        "utf-8 bom",
+			contentType: "application/json",
+			body:        append([]byte{0xEF, 0xBB, 0xBF}, `{"title":"你好"}`...),
+			want:        `{"title":"你好"}`,
+		},
+		{
+			name:              "gbk",
+			contentType:       "application/json; charset=GBK",
+			body:              append(append([]byte(`{"title":"`), 0xC4, 0xE3, 0xBA, 0xC3), `"}`...),
+			want:              `{"title":"你好"}`,
+			wantLengthDropped: true,
+		},
+		{
+			name:              "utf-16le bom",
+			contentType:       "application/json",
+			body:              []byte{0xFF, 0xFE, '{', 0, '}', 0},
+			want:              `{}`,
+			wantLengthDropped: true,
+		},
+		{
+			name:        "unknown charset",
+			contentType: "application/json; charset=not-a-charset",
+			body:        []byte(`{}`),
+			want:        `{}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			base := roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+				header := http.Header{}
+				header.Set("Content-Type", test.contentType)
+				header.Set("Content-Length", "100")
+
+				return &http.Response{
+					StatusCode:    http.StatusOK,
+					Header:        header,
+					ContentLength: int64(len(test.body)),
+					Body:          io.NopCloser(bytes.NewReader(test.body)),
+				}, nil
+			})
+
+			client := withCharsetTranscoding(&http.Client{Transport: base})
+
+			response, err := client.Get("http://glance.test/feed")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer response.Body.Close()
+
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatalf("reading body: %v", err)
+			}
+
+			if string(body) != test.want {
+				t.Errorf("expected body %q, got %q", test.want, body)
+			}
+
+			if lengthDropped := response.ContentLength == -1 && response.Header.Get("Content-Length") == ""; lengthDropped != test.wantLengthDropped {
+				t.Errorf("expected the content length to be dropped: %v, got length %d", test.wantLengthDropped, response.ContentLength)
+			}
+		})
+	}
+}
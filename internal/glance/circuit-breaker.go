@@ -0,0 +1,72 @@
+package glance
+
+import "time"
+
+const (
+	defaultCircuitBreakerFailureRatio = 0.5
+	maxCircuitBreakerMultiplier       = 16
+)
+
+type circuitBreakerField struct {
+	// the share of feeds that need to fail in a single update to trip it
+	FailureRatio float64 `yaml:"failure-ratio"`
+	// the longest the refresh interval can get stretched to
+	MaxInterval durationField `yaml:"max-interval"`
+}
+
+// Stretches the refresh interval while a large share of the widget's feeds
+// keeps failing, doubling it with every such update and halving it again with
+// every update where enough of them succeed
+func (w *widgetBase) recordFeedFailures(failed int, total int) {
+	if w.CircuitBreaker == nil || total == 0 {
+		return
+	}
+
+	if w.circuitBreakerMultiplier < 1 {
+		w.circuitBreakerMultiplier = 1
+	}
+
+	failureRatio := w.CircuitBreaker.FailureRatio
+	if failureRatio <= 0 {
+		failureRatio = defaultCircuitBreakerFailureRatio
+	}
+
+	if float64(failed)/float64(total) < failureRatio {
+		if w.circuitBreakerMultiplier > 1 {
+			w.circuitBreakerMultiplier /= 2
+		}
+
+		return
+	}
+
+	if w.circuitBreakerMultiplier >= maxCircuitBreakerMultiplier {
+		return
+	}
+
+	maxInterval := time.Duration(w.CircuitBreaker.MaxInterval)
+	if maxInterval > 0 && w.cacheDuration*time.Duration(w.circuitBreakerMultiplier) >= maxInterval {
+		return
+	}
+
+	w.circuitBreakerMultiplier *= 2
+}
+
+func (w *widgetBase) circuitBreakerInterval() time.Duration {
+	interval := w.cacheDuration * time.Duration(w.circuitBreakerMultiplier)
+
+	if maxInterval := time.Duration(w.CircuitBreaker.MaxInterval); maxInterval > 0 && interval > maxInterval {
+		interval = maxInterval
+	}
+
+	return interval
+}
+
+// Pushes the next update back while the breaker is tripped, which takes
+// precedence over early retries since those would only add to the load
+func (w *widgetBase) applyCircuitBreaker() {
+	if w.CircuitBreaker == nil || w.circuitBreakerMultiplier <= 1 || w.cacheType != cacheTypeDuration {
+		return
+	}
+
+	w.nextUpdate = time.Now().Add(w.circuitBreakerInterval())
+}
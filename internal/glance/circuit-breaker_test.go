@@ -0,0 +1,60 @@
+package glance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	// how many of the two feeds fail, starting with the first one
+	var failing atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/a" && failing.Load() >= 1 || r.URL.Path == "/b" && failing.Load() >= 2 {
+			http.Error(w, "rsshub is down", http.StatusBadGateway)
+			return
+		}
+
+		w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("BV1", "Video", time.Now()))))
+	}))
+	defer server.Close()
+
+	widget := newTestBilibiliVideosWidget(t, `
+cache: 1h
+circuit-breaker:
+  failure-ratio: 0.5
+  max-interval: 6h
+rsshuburls:
+  - `+server.URL+`/a
+  - `+server.URL+`/b
+`)
+
+	steps := []struct {
+		failing int32
+		want    time.Duration
+	}{
+		{0, time.Hour},
+		{2, 2 * time.Hour},
+		// half of the feeds failing is as many as the ratio
+		{1, 4 * time.Hour},
+		// doubling it again would go over the max interval
+		{2, 6 * time.Hour},
+		{2, 6 * time.Hour},
+		{0, 4 * time.Hour},
+		{0, 2 * time.Hour},
+		{0, time.Hour},
+	}
+
+	for i, step := range steps {
+		failing.Store(step.failing)
+		widget.update(context.Background())
+
+		if got := time.Until(widget.nextUpdate); got > step.want || got < step.want-time.Minute {
+			t.Errorf("step %d: expected the next update in %v, got %v", i, step.want, got)
+		}
+	}
+}
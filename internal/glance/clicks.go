@@ -0,0 +1,90 @@
+package glance
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Implemented by widgets that let users opt into having clicks on their
+// items recorded. Only clicks on items the widget is currently showing get
+// recorded, so that the counts can't be filled with arbitrary URLs
+type clickTrackingWidget interface {
+	tracksClicks() bool
+	isShowingUrl(url string) bool
+}
+
+type clickKey struct {
+	widgetID uint64
+	url      string
+}
+
+// Only the widget and the URL of the clicked item are recorded, nothing that
+// could identify who clicked it
+type clickCounter struct {
+	mu     sync.Mutex
+	counts map[clickKey]int
+}
+
+func (c *clickCounter) record(widgetID uint64, url string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[clickKey]int)
+	}
+
+	key := clickKey{widgetID: widgetID, url: url}
+	c.counts[key]++
+
+	return c.counts[key]
+}
+
+type clickRequestJson struct {
+	WidgetID uint64 `json:"widget-id"`
+	URL      string `json:"url"`
+}
+
+func (a *application) handleClickRequest(w http.ResponseWriter, r *http.Request) {
+	var click clickRequestJson
+
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&click); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	widget, exists := a.widgetByID[click.WidgetID]
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	tracking, ok := widget.(clickTrackingWidget)
+	if !ok || !tracking.tracksClicks() {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if parsedURL, err := url.Parse(click.URL); err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	page := a.widgetPages[click.WidgetID]
+	page.mu.Lock()
+	showing := tracking.isShowingUrl(click.URL)
+	page.mu.Unlock()
+
+	if !showing {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	count := a.clicks.record(click.WidgetID, click.URL)
+	slog.Info("Recorded click", "widget", click.WidgetID, "url", click.URL, "count", count)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,84 @@
+package glance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClickRequest(t *testing.T) {
+	config, err := newConfigFromYAML([]byte(`
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: bilibili-videos
+            id: tracked
+            track-clicks: true
+            rsshuburls: [https://rsshub.example/bilibili/user/video/1]
+          - type: bilibili-videos
+            id: untracked
+            rsshuburls: [https://rsshub.example/bilibili/user/video/1]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	app, err := newApplication(config)
+	if err != nil {
+		t.Fatalf("creating application: %v", err)
+	}
+
+	const videoUrl = "https://www.bilibili.com/video/BV1"
+
+	for _, id := range []string{"tracked", "untracked"} {
+		widget := app.widgetByConfigID[id].(*bilibiliVideosWidget)
+		widget.Videos = bilibiliVideoList{{Title: "Video", Url: videoUrl, TimePosted: time.Now()}}
+	}
+
+	tracked := app.widgetByConfigID["tracked"].GetID()
+	untracked := app.widgetByConfigID["untracked"].GetID()
+
+	click := func(widgetID uint64, url string) string {
+		return `{"widget-id": ` + strconv.FormatUint(widgetID, 10) + `, "url": "` + url + `"}`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "shown video", body: click(tracked, videoUrl), wantStatus: http.StatusNoContent, wantCount: 1},
+		{name: "same video again", body: click(tracked, videoUrl), wantStatus: http.StatusNoContent, wantCount: 2},
+		{name: "video that isn't shown", body: click(tracked, "https://www.bilibili.com/video/BV2"), wantStatus: http.StatusBadRequest, wantCount: 2},
+		{name: "not an http url", body: click(tracked, "javascript:alert(1)"), wantStatus: http.StatusBadRequest, wantCount: 2},
+		{name: "widget that doesn't track clicks", body: click(untracked, videoUrl), wantStatus: http.StatusForbidden, wantCount: 2},
+		{name: "unknown widget", body: click(999999, videoUrl), wantStatus: http.StatusNotFound, wantCount: 2},
+		{name: "malformed body", body: "{", wantStatus: http.StatusBadRequest, wantCount: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "/api/click", strings.NewReader(test.body))
+			recorder := httptest.NewRecorder()
+			app.handleClickRequest(recorder, request)
+
+			if recorder.Code != test.wantStatus {
+				t.Errorf("expected status %d, got %d", test.wantStatus, recorder.Code)
+			}
+
+			if count := app.clicks.counts[clickKey{widgetID: tracked, url: videoUrl}]; count != test.wantCount {
+				t.Errorf("expected %d recorded clicks, got %d", test.wantCount, count)
+			}
+
+			if len(app.clicks.counts) > 1 {
+				t.Errorf("expected only clicks on the shown video to be recorded, got %v", app.clicks.counts)
+			}
+		})
+	}
+}
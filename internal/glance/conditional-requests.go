@@ -0,0 +1,103 @@
+package glance
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+var errNotModified = errors.New("content not modified since the last request")
+
+type conditionalRequestValidators struct {
+	etag         string
+	lastModified string
+}
+
+// Makes requests conditional using the validators of earlier responses for
+// the same URL. Validators only get sent once they're committed, which should
+// happen after the content of the response has been stored somewhere, since
+// there is nothing to fall back to when the server responds with 304 otherwise
+type conditionalTransport struct {
+	base http.RoundTripper
+
+	mu        sync.Mutex
+	committed map[string]conditionalRequestValidators
+	received  map[string]conditionalRequestValidators
+}
+
+func newConditionalTransport(base http.RoundTripper) *conditionalTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &conditionalTransport{
+		base:      base,
+		committed: make(map[string]conditionalRequestValidators),
+		received:  make(map[string]conditionalRequestValidators),
+	}
+}
+
+func (t *conditionalTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	url := request.URL.String()
+
+	t.mu.Lock()
+	validators, exists := t.committed[url]
+	t.mu.Unlock()
+
+	if exists {
+		request = request.Clone(request.Context())
+
+		if validators.etag != "" {
+			request.Header.Set("If-None-Match", validators.etag)
+		}
+
+		if validators.lastModified != "" {
+			request.Header.Set("If-Modified-Since", validators.lastModified)
+		}
+	}
+
+	response, err := t.base.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusNotModified {
+		response.Body.Close()
+		return nil, errNotModified
+	}
+
+	if response.StatusCode == http.StatusOK {
+		received := conditionalRequestValidators{
+			etag:         response.Header.Get("ETag"),
+			lastModified: response.Header.Get("Last-Modified"),
+		}
+
+		if received.etag != "" || received.lastModified != "" {
+			t.mu.Lock()
+			t.received[url] = received
+			t.mu.Unlock()
+		}
+	}
+
+	return response, nil
+}
+
+// Starts sending the validators last received for the URL with its requests
+func (t *conditionalTransport) commit(url string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if validators, exists := t.received[url]; exists {
+		t.committed[url] = validators
+		delete(t.received, url)
+	}
+}
+
+func withConditionalRequests(client *http.Client) (*http.Client, *conditionalTransport) {
+	transport := newConditionalTransport(client.Transport)
+
+	conditional := *client
+	conditional.Transport = transport
+
+	return &conditional, transport
+}
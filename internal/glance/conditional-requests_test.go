@@ -0,0 +1,74 @@
+package glance
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConditionalTransport(t *testing.T) {
+	var lastIfNoneMatch, lastIfModifiedSince string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastIfNoneMatch = r.Header.Get("If-None-Match")
+		lastIfModifiedSince = r.Header.Get("If-Modified-Since")
+
+		if lastIfNoneMatch == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Fri, 10 May 2024 12:00:00 GMT")
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	client, transport := withConditionalRequests(server.Client())
+
+	get := func() error {
+		response, err := client.Get(server.URL)
+		if err != nil {
+			return err
+		}
+
+		response.Body.Close()
+		return nil
+	}
+
+	tests := []struct {
+		name            string
+		commitBefore    bool
+		wantIfNoneMatch string
+		wantErr         error
+	}{
+		{name: "first request"},
+		// the content of the first response hasn't been stored anywhere yet
+		{name: "before committing"},
+		{name: "after committing", commitBefore: true, wantIfNoneMatch: `"v1"`, wantErr: errNotModified},
+		{name: "stays committed", wantIfNoneMatch: `"v1"`, wantErr: errNotModified},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.commitBefore {
+				transport.commit(server.URL)
+			}
+
+			err := get()
+
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+
+			if lastIfNoneMatch != test.wantIfNoneMatch {
+				t.Errorf("expected If-None-Match %q, got %q", test.wantIfNoneMatch, lastIfNoneMatch)
+			}
+
+			if (test.wantIfNoneMatch != "") != (lastIfModifiedSince != "") {
+				t.Errorf("expected If-Modified-Since to be sent along with If-None-Match, got %q", lastIfModifiedSince)
+			}
+		})
+	}
+}
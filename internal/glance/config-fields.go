@@ -114,6 +114,77 @@ func (d *durationField) UnmarshalYAML(node *yaml.Node) error {
 	return nil
 }
 
+var timeOfDayFieldPattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+
+type quietHoursField struct {
+	Start    string         `yaml:"start"`
+	End      string         `yaml:"end"`
+	Timezone string         `yaml:"timezone"`
+	start    time.Duration  `yaml:"-"`
+	end      time.Duration  `yaml:"-"`
+	location *time.Location `yaml:"-"`
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	matches := timeOfDayFieldPattern.FindStringSubmatch(value)
+	if len(matches) != 3 {
+		return 0, fmt.Errorf("invalid time format: %s, expected HH:MM", value)
+	}
+
+	hours, _ := strconv.Atoi(matches[1])
+	minutes, _ := strconv.Atoi(matches[2])
+
+	if hours > 23 || minutes > 59 {
+		return 0, fmt.Errorf("invalid time: %s", value)
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+func (q *quietHoursField) UnmarshalYAML(node *yaml.Node) error {
+	type quietHoursFieldAlias quietHoursField
+	alias := (*quietHoursFieldAlias)(q)
+
+	if err := node.Decode(alias); err != nil {
+		return err
+	}
+
+	var err error
+
+	if q.start, err = parseTimeOfDay(q.Start); err != nil {
+		return fmt.Errorf("quiet-hours start: %v", err)
+	}
+
+	if q.end, err = parseTimeOfDay(q.End); err != nil {
+		return fmt.Errorf("quiet-hours end: %v", err)
+	}
+
+	q.location = time.Local
+	if q.Timezone != "" {
+		if q.location, err = time.LoadLocation(q.Timezone); err != nil {
+			return fmt.Errorf("quiet-hours timezone: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (q *quietHoursField) contains(t time.Time) bool {
+	if q.location == nil || q.start == q.end {
+		return false
+	}
+
+	t = t.In(q.location)
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+
+	// quiet hours can span midnight, e.g. 23:00 to 07:00
+	if q.start < q.end {
+		return sinceMidnight >= q.start && sinceMidnight < q.end
+	}
+
+	return sinceMidnight >= q.start || sinceMidnight < q.end
+}
+
 type customIconField struct {
 	URL        string
 	IsFlatIcon bool
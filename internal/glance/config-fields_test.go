@@ -0,0 +1,44 @@
+package glance
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestQuietHoursContains(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 10, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		time   time.Time
+		want   bool
+	}{
+		{"overnight before", "{start: '23:00', end: '07:00', timezone: UTC}", at(22, 59), false},
+		{"overnight start", "{start: '23:00', end: '07:00', timezone: UTC}", at(23, 0), true},
+		{"overnight past midnight", "{start: '23:00', end: '07:00', timezone: UTC}", at(2, 30), true},
+		{"overnight end", "{start: '23:00', end: '07:00', timezone: UTC}", at(7, 0), false},
+		{"daytime", "{start: '09:00', end: '17:00', timezone: UTC}", at(12, 0), true},
+		{"daytime after", "{start: '09:00', end: '17:00', timezone: UTC}", at(18, 0), false},
+		// 02:00 in Shanghai is 18:00 in UTC
+		{"timezone", "{start: '01:00', end: '03:00', timezone: Asia/Shanghai}", at(18, 0), true},
+		{"empty range", "{start: '09:00', end: '09:00', timezone: UTC}", at(9, 0), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var quietHours quietHoursField
+			if err := yaml.Unmarshal([]byte(test.config), &quietHours); err != nil {
+				t.Fatalf("unmarshaling quiet hours: %v", err)
+			}
+
+			if got := quietHours.contains(test.time); got != test.want {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
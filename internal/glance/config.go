@@ -19,13 +19,24 @@ import (
 
 type config struct {
 	Server struct {
-		Host       string    `yaml:"host"`
-		Port       uint16    `yaml:"port"`
-		AssetsPath string    `yaml:"assets-path"`
-		BaseURL    string    `yaml:"base-url"`
-		StartedAt  time.Time `yaml:"-"` // used in custom css file
+		Host               string        `yaml:"host"`
+		Port               uint16        `yaml:"port"`
+		AssetsPath         string        `yaml:"assets-path"`
+		BaseURL            string        `yaml:"base-url"`
+		ForceHTTP1         bool          `yaml:"force-http1"`
+		RefreshMergeWindow durationField `yaml:"refresh-merge-window"`
+		Debug              bool          `yaml:"debug"`
+		Warmup             bool          `yaml:"warmup"`
+		WarmupConcurrency  int           `yaml:"warmup-concurrency"`
+		EmbeddableWidgets  []string      `yaml:"embeddable-widgets"`
+		// either default or a policy of your own, in which {nonce} gets replaced
+		// with the nonce inline blocks are allowed with
+		ContentSecurityPolicy string    `yaml:"content-security-policy"`
+		StartedAt             time.Time `yaml:"-"` // used in custom css file
 	} `yaml:"server"`
 
+	Auth authConfig `yaml:"auth"`
+
 	Document struct {
 		Head template.HTML `yaml:"head"`
 	} `yaml:"document"`
@@ -60,6 +71,9 @@ type page struct {
 	ExpandMobilePageNavigation bool   `yaml:"expand-mobile-page-navigation"`
 	HideDesktopNavigation      bool   `yaml:"hide-desktop-navigation"`
 	CenterVertically           bool   `yaml:"center-vertically"`
+	PreloadCount               int    `yaml:"preload-count"`
+	DocumentTitle              string `yaml:"document-title"`
+	FaviconURL                 string `yaml:"favicon-url"`
 	Columns                    []struct {
 		Size    string  `yaml:"size"`
 		Widgets widgets `yaml:"widgets"`
@@ -76,12 +90,23 @@ func newConfigFromYAML(contents []byte) (*config, error) {
 
 	config := &config{}
 	config.Server.Port = 8080
+	config.Server.WarmupConcurrency = 10
 
-	err = yaml.Unmarshal(contents, config)
-	if err != nil {
+	var document yaml.Node
+	if err = yaml.Unmarshal(contents, &document); err != nil {
 		return nil, err
 	}
 
+	if len(document.Content) > 0 {
+		if err = includeConfigFragments(document.Content[0]); err != nil {
+			return nil, err
+		}
+
+		if err = document.Decode(config); err != nil {
+			return nil, err
+		}
+	}
+
 	if err = isConfigStateValid(config); err != nil {
 		return nil, err
 	}
@@ -89,7 +114,7 @@ func newConfigFromYAML(contents []byte) (*config, error) {
 	for p := range config.Pages {
 		for c := range config.Pages[p].Columns {
 			for w := range config.Pages[p].Columns[c].Widgets {
-				if err := config.Pages[p].Columns[c].Widgets[w].initialize(); err != nil {
+				if err := initializeWidget(config.Pages[p].Columns[c].Widgets[w]); err != nil {
 					return nil, formatWidgetInitError(err, config.Pages[p].Columns[c].Widgets[w])
 				}
 			}
@@ -99,6 +124,111 @@ func newConfigFromYAML(contents []byte) (*config, error) {
 	return config, nil
 }
 
+// Merges the named fragments defined under the top level fragments property
+// into every widget that references one through its include property, with
+// the properties of the widget itself taking precedence
+func includeConfigFragments(root *yaml.Node) error {
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	fragments := make(map[string]*yaml.Node)
+	var pages *yaml.Node
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		switch root.Content[i].Value {
+		case "fragments":
+			node := root.Content[i+1]
+			if node.Kind != yaml.MappingNode {
+				return fmt.Errorf("line %d: fragments must be a mapping of names to widget properties", node.Line)
+			}
+
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j+1].Kind != yaml.MappingNode {
+					return fmt.Errorf("line %d: fragment %s must be a mapping", node.Content[j].Line, node.Content[j].Value)
+				}
+
+				fragments[node.Content[j].Value] = node.Content[j+1]
+			}
+		case "pages":
+			pages = root.Content[i+1]
+		}
+	}
+
+	if pages == nil {
+		return nil
+	}
+
+	return includeFragmentsInWidgets(pages, fragments)
+}
+
+func includeFragmentsInWidgets(node *yaml.Node, fragments map[string]*yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != "widgets" || node.Content[i+1].Kind != yaml.SequenceNode {
+				continue
+			}
+
+			for _, widget := range node.Content[i+1].Content {
+				if err := includeFragmentInWidget(widget, fragments); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// widgets such as groups and split columns have widgets of their own
+	for _, child := range node.Content {
+		if err := includeFragmentsInWidgets(child, fragments); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func includeFragmentInWidget(widget *yaml.Node, fragments map[string]*yaml.Node) error {
+	if widget.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	includeIndex := -1
+	properties := make(map[string]struct{}, len(widget.Content)/2)
+
+	for i := 0; i+1 < len(widget.Content); i += 2 {
+		if widget.Content[i].Value == "include" {
+			includeIndex = i
+			continue
+		}
+
+		properties[widget.Content[i].Value] = struct{}{}
+	}
+
+	if includeIndex == -1 {
+		return nil
+	}
+
+	name := widget.Content[includeIndex+1].Value
+	fragment, exists := fragments[name]
+	if !exists {
+		return fmt.Errorf("line %d: fragment %s is not defined", widget.Content[includeIndex].Line, name)
+	}
+
+	content := make([]*yaml.Node, 0, len(widget.Content)+len(fragment.Content))
+	content = append(content, widget.Content[:includeIndex]...)
+	content = append(content, widget.Content[includeIndex+2:]...)
+
+	for i := 0; i+1 < len(fragment.Content); i += 2 {
+		if _, exists := properties[fragment.Content[i].Value]; !exists {
+			content = append(content, fragment.Content[i], fragment.Content[i+1])
+		}
+	}
+
+	widget.Content = content
+
+	return nil
+}
+
 // TODO: change the pattern so that it doesn't match commented out lines
 var configEnvVariablePattern = regexp.MustCompile(`(^|.)\$\{([A-Z0-9_]+)\}`)
 
@@ -345,6 +475,10 @@ func isConfigStateValid(config *config) error {
 		return fmt.Errorf("no pages configured")
 	}
 
+	if err := config.Auth.validate(); err != nil {
+		return err
+	}
+
 	if config.Server.AssetsPath != "" {
 		if _, err := os.Stat(config.Server.AssetsPath); os.IsNotExist(err) {
 			return fmt.Errorf("assets directory does not exist: %s", config.Server.AssetsPath)
@@ -0,0 +1,77 @@
+package glance
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConfigFragments(t *testing.T) {
+	config, err := newConfigFromYAML([]byte(`
+fragments:
+  followed:
+    rsshuburls:
+      - https://rsshub.example/bilibili/user/video/1
+      - https://rsshub.example/bilibili/user/video/2
+    style: grid-cards
+    limit: 12
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: bilibili-videos
+            include: followed
+          - type: bilibili-videos
+            include: followed
+            style: vertical-list
+          - type: group
+            widgets:
+              - type: bilibili-videos
+                include: followed
+                limit: 4
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	widgets := config.Pages[0].Columns[0].Widgets
+	included := []*bilibiliVideosWidget{
+		widgets[0].(*bilibiliVideosWidget),
+		widgets[1].(*bilibiliVideosWidget),
+		widgets[2].(*groupWidget).Widgets[0].(*bilibiliVideosWidget),
+	}
+
+	wantFeeds := []string{"https://rsshub.example/bilibili/user/video/1", "https://rsshub.example/bilibili/user/video/2"}
+
+	for i, widget := range included {
+		if got := widget.feedUrls(); !slices.Equal(got, wantFeeds) {
+			t.Errorf("widget %d: expected the feeds %v, got %v", i, wantFeeds, got)
+		}
+	}
+
+	// the properties of the widget itself take precedence over the fragment's
+	if included[0].Style != "grid-cards" || included[1].Style != "vertical-list" {
+		t.Errorf("expected the styles grid-cards and vertical-list, got %s and %s", included[0].Style, included[1].Style)
+	}
+
+	if included[0].Limit != 12 || included[2].Limit != 4 {
+		t.Errorf("expected the limits 12 and 4, got %d and %d", included[0].Limit, included[2].Limit)
+	}
+}
+
+func TestConfigFragmentsUndefined(t *testing.T) {
+	_, err := newConfigFromYAML([]byte(`
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: bilibili-videos
+            include: missing
+`))
+
+	if err == nil || !strings.Contains(err.Error(), "fragment missing is not defined") {
+		t.Errorf("expected an undefined fragment error, got %v", err)
+	}
+}
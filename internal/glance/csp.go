@@ -0,0 +1,79 @@
+package glance
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Inline style attributes are used all over the templates and, unlike blocks,
+// can't carry a nonce, so they still have to be allowed
+const defaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'nonce-{nonce}'; " +
+	"style-src 'self' 'nonce-{nonce}'; " +
+	"style-src-attr 'unsafe-inline'; " +
+	"img-src * data:; " +
+	"frame-src *"
+
+// Stands in for the nonce in the inline blocks of HTML that comes from the
+// config, such as that of html and custom-api widgets. Rendered widgets get reused across
+// requests that each have their own nonce, so it gets swapped in as the
+// content is served. It's random so that nothing fed into a widget can
+// give itself the nonce by guessing it
+var contentSecurityPolicyNoncePlaceholder = newContentSecurityPolicyNoncePlaceholder()
+
+var inlineBlockTagPattern = regexp.MustCompile(`(?i)<(script|style)\b`)
+
+func newContentSecurityPolicyNoncePlaceholder() string {
+	placeholder := make([]byte, 8)
+	rand.Read(placeholder)
+
+	return "glance-nonce-" + hex.EncodeToString(placeholder)
+}
+
+func newContentSecurityPolicyNonce() string {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+
+	return base64.StdEncoding.EncodeToString(nonce)
+}
+
+// Sets the policy header when enabled and returns the nonce it allows inline
+// blocks with, or an empty string when it's disabled
+func (a *application) setContentSecurityPolicy(w http.ResponseWriter) string {
+	policy := a.Config.Server.ContentSecurityPolicy
+	if policy == "" {
+		return ""
+	}
+
+	if policy == "default" {
+		policy = defaultContentSecurityPolicy
+	}
+
+	nonce := newContentSecurityPolicyNonce()
+	w.Header().Set("Content-Security-Policy", strings.ReplaceAll(policy, "{nonce}", nonce))
+
+	return nonce
+}
+
+// Marks the inline blocks of HTML from the config so that they get the nonce
+// of whichever request they end up being served with. Only ever meant for
+// HTML that the config is in control of, never for anything fetched
+func withNoncePlaceholders(html string) string {
+	return inlineBlockTagPattern.ReplaceAllString(html, `<$1 nonce="`+contentSecurityPolicyNoncePlaceholder+`"`)
+}
+
+// Swaps the placeholders for the nonce, dropping the attribute altogether
+// when there is no policy
+func withContentSecurityPolicyNonce(html string, nonce string) string {
+	attribute := ` nonce="` + contentSecurityPolicyNoncePlaceholder + `"`
+
+	if nonce == "" {
+		return strings.ReplaceAll(html, attribute, "")
+	}
+
+	return strings.ReplaceAll(html, attribute, ` nonce="`+nonce+`"`)
+}
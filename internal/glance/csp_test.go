@@ -0,0 +1,236 @@
+package glance
+
+import (
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSetContentSecurityPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		// {nonce} gets replaced with the returned nonce before comparing
+		want string
+	}{
+		{
+			name:   "disabled",
+			policy: "",
+			want:   "",
+		},
+		{
+			name:   "default",
+			policy: "default",
+			want:   defaultContentSecurityPolicy,
+		},
+		{
+			name:   "custom",
+			policy: "script-src 'self' 'nonce-{nonce}'",
+			want:   "script-src 'self' 'nonce-{nonce}'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &application{}
+			app.Config.Server.ContentSecurityPolicy = test.policy
+
+			recorder := httptest.NewRecorder()
+			nonce := app.setContentSecurityPolicy(recorder)
+			header := recorder.Header().Get("Content-Security-Policy")
+
+			if test.want == "" {
+				if nonce != "" || header != "" {
+					t.Fatalf("expected no nonce and no header, got %q and %q", nonce, header)
+				}
+
+				return
+			}
+
+			if nonce == "" {
+				t.Fatal("expected a nonce")
+			}
+
+			if want := strings.ReplaceAll(test.want, "{nonce}", nonce); header != want {
+				t.Errorf("expected header %q, got %q", want, header)
+			}
+		})
+	}
+}
+
+func TestContentSecurityPolicyNoncesAreUnique(t *testing.T) {
+	app := &application{}
+	app.Config.Server.ContentSecurityPolicy = "default"
+
+	seen := make(map[string]struct{})
+
+	for range 100 {
+		nonce := app.setContentSecurityPolicy(httptest.NewRecorder())
+		if _, exists := seen[nonce]; exists {
+			t.Fatalf("nonce %q was handed out twice", nonce)
+		}
+
+		seen[nonce] = struct{}{}
+	}
+}
+
+var nonceAttributePattern = regexp.MustCompile(` nonce="([^"]*)"`)
+
+var inlineBlockTagsPattern = regexp.MustCompile(`(?i)<(?:script|style)\b[^>]*>`)
+
+// Returns the opening tags of every inline script and style block
+func inlineBlockTags(html string) []string {
+	var tags []string
+
+	for _, tag := range inlineBlockTagsPattern.FindAllString(html, -1) {
+		if !strings.Contains(tag, " src=") {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+func newTestContentSecurityPolicyApp(t *testing.T) *application {
+	t.Helper()
+
+	config, err := newConfigFromYAML([]byte(`
+server:
+  content-security-policy: default
+  embeddable-widgets: [html-block]
+document:
+  head: |
+    <script>window.fromHead = true;</script>
+    <style>.from-head { color: red; }</style>
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: html
+            id: html-block
+            source: |
+              <style>.from-widget { color: blue; }</style><p>hello</p>
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	app, err := newApplication(config)
+	if err != nil {
+		t.Fatalf("creating application: %v", err)
+	}
+
+	return app
+}
+
+func TestInlineBlocksCarryTheNonceOfTheHeader(t *testing.T) {
+	app := newTestContentSecurityPolicyApp(t)
+
+	tests := []struct {
+		name    string
+		path    string
+		handler func(w http.ResponseWriter, r *http.Request)
+		values  map[string]string
+		// blocks that have to be among those on the page
+		want []string
+	}{
+		{
+			name:    "page",
+			path:    "/home",
+			handler: app.handlePageRequest,
+			values:  map[string]string{"page": "home"},
+			want:    []string{"window.fromHead", ".from-head"},
+		},
+		{
+			name:    "embedded widget",
+			path:    "/embed/widget/html-block",
+			handler: app.handleEmbedRequest,
+			values:  map[string]string{"widget": "html-block"},
+			want:    []string{".from-widget"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", test.path, nil)
+			for key, value := range test.values {
+				request.SetPathValue(key, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			test.handler(recorder, request)
+
+			body := recorder.Body.String()
+			header := recorder.Header().Get("Content-Security-Policy")
+			nonce := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(header)
+			if nonce == nil {
+				t.Fatalf("expected a nonce in the header, got %q", header)
+			}
+
+			for _, want := range test.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected the response to contain %q", want)
+				}
+			}
+
+			tags := inlineBlockTags(body)
+			if len(tags) == 0 {
+				t.Fatal("expected inline blocks in the response")
+			}
+
+			for _, tag := range tags {
+				// the nonce can contain characters that get escaped in attributes
+				attribute := nonceAttributePattern.FindStringSubmatch(tag)
+				if attribute == nil || html.UnescapeString(attribute[1]) != nonce[1] {
+					t.Errorf("expected %s to carry the nonce %q", tag, nonce[1])
+				}
+			}
+		})
+	}
+}
+
+func TestPageContentInlineBlocksCarryTheNoncePlaceholder(t *testing.T) {
+	app := newTestContentSecurityPolicyApp(t)
+
+	request := httptest.NewRequest("GET", "/api/pages/home/content/", nil)
+	request.SetPathValue("page", "home")
+	recorder := httptest.NewRecorder()
+	app.handlePageContentRequest(recorder, request)
+
+	// the page's script swaps the placeholder for the nonce of the page
+	tags := inlineBlockTags(recorder.Body.String())
+	if len(tags) == 0 {
+		t.Fatal("expected inline blocks in the content")
+	}
+
+	for _, tag := range tags {
+		if !strings.Contains(tag, ` nonce="`+contentSecurityPolicyNoncePlaceholder+`"`) {
+			t.Errorf("expected %s to carry the nonce placeholder", tag)
+		}
+	}
+}
+
+func TestWithContentSecurityPolicyNonce(t *testing.T) {
+	marked := withNoncePlaceholders(`<style>a{}</style><SCRIPT src="/a.js"></SCRIPT><p>text <scripted></p>`)
+
+	tests := []struct {
+		name  string
+		nonce string
+		want  string
+	}{
+		{"with a policy", "abc", `<style nonce="abc">a{}</style><SCRIPT nonce="abc" src="/a.js"></SCRIPT><p>text <scripted></p>`},
+		{"without a policy", "", `<style>a{}</style><SCRIPT src="/a.js"></SCRIPT><p>text <scripted></p>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := withContentSecurityPolicyNonce(marked, test.nonce); got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
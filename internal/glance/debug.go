@@ -0,0 +1,131 @@
+package glance
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const maxDebugFeedBodySize = 5 * 1024 * 1024
+
+var errFeedIndexOutOfRange = errors.New("feed index out of range")
+
+type feedDebugInfo struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status-code"`
+	Body       string `json:"body"`
+	Parsed     any    `json:"parsed,omitempty"`
+	ParseError string `json:"parse-error,omitempty"`
+	// the percentage of recent updates the feed was fetched successfully in
+	Health *float64 `json:"health,omitempty"`
+}
+
+// Implemented by widgets that can show exactly what was fetched and parsed
+// for each of their feeds
+type feedDebuggingWidget interface {
+	// Called with the widget's page locked, so it should only copy what's
+	// needed from the widget and leave the fetching to the returned request
+	feedDebugRequest(index int) (*feedDebugRequest, error)
+}
+
+type feedDebugRequest struct {
+	client  *http.Client
+	request *http.Request
+	// turns the body of the response into what gets shown as parsed
+	parse  func(body []byte) (any, error)
+	health func() (float64, bool)
+}
+
+func (r *feedDebugRequest) fetch() (*feedDebugInfo, error) {
+	info, body, err := fetchFeedForDebugging(r.client, r.request)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed, err := r.parse(body); err != nil {
+		info.ParseError = err.Error()
+	} else {
+		info.Parsed = parsed
+	}
+
+	if health, exists := r.health(); exists {
+		info.Health = &health
+	}
+
+	return info, nil
+}
+
+func fetchFeedForDebugging(client *http.Client, request *http.Request) (*feedDebugInfo, []byte, error) {
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxDebugFeedBodySize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &feedDebugInfo{
+		URL:        request.URL.String(),
+		StatusCode: response.StatusCode,
+		Body:       string(body),
+	}, body, nil
+}
+
+func (a *application) handleDebugFeedRequest(w http.ResponseWriter, r *http.Request) {
+	widgetID, err := strconv.ParseUint(r.PathValue("widget"), 10, 64)
+	if err != nil {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	feedIndex, err := strconv.Atoi(r.PathValue("feed"))
+	if err != nil {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	widget, exists := a.widgetByID[widgetID]
+	if !exists {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	debugging, ok := widget.(feedDebuggingWidget)
+	if !ok {
+		http.Error(w, "widget does not support feed debugging", http.StatusBadRequest)
+		return
+	}
+
+	// the widget's client and feeds can't be touched while it's updating, but
+	// there's no need to hold up updates and page loads for the fetch itself
+	page := a.widgetPages[widgetID]
+	page.mu.Lock()
+	request, err := debugging.feedDebugRequest(feedIndex)
+	page.mu.Unlock()
+
+	if errors.Is(err, errFeedIndexOutOfRange) {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	info, err := request.fetch()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(info)
+}
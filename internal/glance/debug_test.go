@@ -0,0 +1,116 @@
+package glance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestDebugFeedRequest(t *testing.T) {
+	var app *application
+	var lockedDuringFetch bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the page shouldn't be held up while the feed is being fetched
+		page := app.slugToPage["home"]
+		if page.mu.TryLock() {
+			page.mu.Unlock()
+		} else {
+			lockedDuringFetch = true
+		}
+
+		w.Write([]byte(`{"items": [{
+			"url": "https://www.bilibili.com/video/BV1",
+			"title": "Debugged video",
+			"date_published": "2024-05-10T12:00:00Z",
+			"authors": [{"name": "Author"}]
+		}]}`))
+	}))
+	defer server.Close()
+
+	config, err := newConfigFromYAML([]byte(`
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: bilibili-videos
+            id: videos
+            rsshuburls: [` + server.URL + `/bilibili/user/video/1]
+          - type: html
+            id: html
+            source: <p></p>
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	app, err = newApplication(config)
+	if err != nil {
+		t.Fatalf("creating application: %v", err)
+	}
+
+	videosID := strconv.FormatUint(app.widgetByConfigID["videos"].GetID(), 10)
+	htmlID := strconv.FormatUint(app.widgetByConfigID["html"].GetID(), 10)
+
+	tests := []struct {
+		name       string
+		widget     string
+		feed       string
+		wantStatus int
+	}{
+		{name: "feed", widget: videosID, feed: "0", wantStatus: http.StatusOK},
+		{name: "feed index out of range", widget: videosID, feed: "1", wantStatus: http.StatusNotFound},
+		{name: "negative feed index", widget: videosID, feed: "-1", wantStatus: http.StatusNotFound},
+		{name: "invalid feed index", widget: videosID, feed: "first", wantStatus: http.StatusNotFound},
+		{name: "unknown widget", widget: "999999", feed: "0", wantStatus: http.StatusNotFound},
+		{name: "widget without feeds", widget: htmlID, feed: "0", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/debug/widget/"+test.widget+"/feed/"+test.feed, nil)
+			request.SetPathValue("widget", test.widget)
+			request.SetPathValue("feed", test.feed)
+
+			recorder := httptest.NewRecorder()
+			app.handleDebugFeedRequest(recorder, request)
+
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", test.wantStatus, recorder.Code, recorder.Body.String())
+			}
+
+			if test.wantStatus != http.StatusOK {
+				return
+			}
+
+			var info struct {
+				URL        string `json:"url"`
+				StatusCode int    `json:"status-code"`
+				ParseError string `json:"parse-error"`
+				Parsed     []struct {
+					Title  string `json:"Title"`
+					Author string `json:"Author"`
+				} `json:"parsed"`
+			}
+
+			if err := json.Unmarshal(recorder.Body.Bytes(), &info); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+
+			if info.URL != server.URL+"/bilibili/user/video/1" || info.StatusCode != http.StatusOK || info.ParseError != "" {
+				t.Errorf("unexpected debug info: %+v", info)
+			}
+
+			if len(info.Parsed) != 1 || info.Parsed[0].Title != "Debugged video" || info.Parsed[0].Author != "Author" {
+				t.Errorf("unexpected parsed videos: %+v", info.Parsed)
+			}
+		})
+	}
+
+	if lockedDuringFetch {
+		t.Error("expected the page not to be locked while the feed was fetched")
+	}
+}
@@ -0,0 +1,108 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type dnsCacheEntry struct {
+	addresses  []string
+	resolvedAt time.Time
+}
+
+// Caches the addresses that hosts resolve to for the given duration so that
+// slow or flaky DNS doesn't get hit on every request. Addresses that have
+// expired are still used if resolving the host again fails
+type dnsCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]dnsCacheEntry
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		ttl:     ttl,
+		entries: make(map[string]dnsCacheEntry),
+	}
+}
+
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, exists := c.entries[host]
+	c.mu.Unlock()
+
+	if exists && time.Since(entry.resolvedAt) < c.ttl {
+		return entry.addresses, nil
+	}
+
+	addresses, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		if exists {
+			return entry.addresses, nil
+		}
+
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = dnsCacheEntry{addresses: addresses, resolvedAt: time.Now()}
+	c.mu.Unlock()
+
+	return addresses, nil
+}
+
+func (c *dnsCache) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	addresses, err := c.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addresses) == 0 {
+		return nil, errors.New("no addresses found for " + host)
+	}
+
+	var errs []error
+
+	for _, ip := range addresses {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (c *dnsCache) newHTTPClient() *http.Client {
+	var transport *http.Transport
+
+	if defaultHTTPClientsForceHTTP1 {
+		transport = newHTTP1Transport(nil)
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	transport.DialContext = c.dialContext
+
+	return &http.Client{
+		Timeout:   defaultClientTimeout,
+		Transport: transport,
+	}
+}
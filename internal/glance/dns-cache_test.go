@@ -0,0 +1,98 @@
+package glance
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDNSCacheResolve(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry *dnsCacheEntry
+		want  []string
+	}{
+		{
+			name:  "fresh entry",
+			entry: &dnsCacheEntry{addresses: []string{"192.0.2.1"}, resolvedAt: time.Now()},
+			want:  []string{"192.0.2.1"},
+		},
+		{
+			// .invalid never resolves, so the expired addresses are all there is
+			name:  "expired entry when resolving fails",
+			entry: &dnsCacheEntry{addresses: []string{"192.0.2.2"}, resolvedAt: time.Now().Add(-2 * time.Hour)},
+			want:  []string{"192.0.2.2"},
+		},
+		{
+			name: "nothing cached when resolving fails",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := newDNSCache(time.Hour)
+			if test.entry != nil {
+				cache.entries["glance.invalid"] = *test.entry
+			}
+
+			addresses, err := cache.resolve(context.Background(), "glance.invalid")
+
+			if test.want == nil {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", addresses)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(addresses, test.want) {
+				t.Errorf("expected %v, got %v", test.want, addresses)
+			}
+		})
+	}
+}
+
+func TestDNSCacheHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newDNSCache(time.Hour)
+	cache.entries["glance.invalid"] = dnsCacheEntry{
+		// the first address refuses connections, the next one gets tried
+		addresses:  []string{"127.0.0.2", "127.0.0.1"},
+		resolvedAt: time.Now(),
+	}
+
+	// listening on every loopback address would make 127.0.0.2 work too
+	if conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.2", port)); err == nil {
+		conn.Close()
+		cache.entries["glance.invalid"] = dnsCacheEntry{addresses: []string{"127.0.0.1"}, resolvedAt: time.Now()}
+	}
+
+	response, err := cache.newHTTPClient().Get("http://glance.invalid:" + port + "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	if string(body) != "ok" {
+		t.Errorf("expected the response of the server, got %q", body)
+	}
+}
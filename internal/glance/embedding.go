@@ -0,0 +1,68 @@
+package glance
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"net/http"
+	"slices"
+	"time"
+)
+
+var embedTemplate = mustParseTemplate("embed.html")
+
+type embedTemplateData struct {
+	App     *application
+	Page    *page
+	Content template.HTML
+	Nonce   string
+}
+
+func (a *application) handleEmbedRequest(w http.ResponseWriter, r *http.Request) {
+	configID := r.PathValue("widget")
+
+	// widgets that aren't allowed to be embedded are treated as if they don't
+	// exist so that the endpoint can't be used to find out which IDs are in use
+	if !slices.Contains(a.Config.Server.EmbeddableWidgets, configID) {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	widget, exists := a.widgetByConfigID[configID]
+	if !exists {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	var err error
+
+	nonce := a.setContentSecurityPolicy(w)
+	var responseBytes bytes.Buffer
+
+	func() {
+		page := a.widgetPages[widget.GetID()]
+		page.mu.Lock()
+		defer page.mu.Unlock()
+
+		now := time.Now()
+		if widget.requiresUpdate(&now) {
+			updateWidget(context.Background(), widget)
+		}
+
+		err = embedTemplate.Execute(&responseBytes, embedTemplateData{
+			App:     a,
+			Page:    page,
+			Content: template.HTML(withContentSecurityPolicyNonce(string(widget.Render()), nonce)),
+			Nonce:   nonce,
+		})
+	}()
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(responseBytes.Bytes())
+}
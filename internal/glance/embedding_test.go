@@ -0,0 +1,139 @@
+package glance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newTestEmbeddingApp(t *testing.T, config string) (*application, error) {
+	t.Helper()
+
+	parsed, err := newConfigFromYAML([]byte(config))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	return newApplication(parsed)
+}
+
+func TestEmbedRequest(t *testing.T) {
+	app, err := newTestEmbeddingApp(t, `
+server:
+  embeddable-widgets: [allowed, nested]
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: html
+            id: allowed
+            source: <p>allowed widget</p>
+          - type: html
+            id: private
+            source: <p>private widget</p>
+          - type: group
+            widgets:
+              - type: html
+                id: nested
+                source: <p>nested widget</p>
+`)
+	if err != nil {
+		t.Fatalf("creating application: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+		want       string
+	}{
+		{name: "allowed", id: "allowed", wantStatus: http.StatusOK, want: "allowed widget"},
+		{name: "nested in a container", id: "nested", wantStatus: http.StatusOK, want: "nested widget"},
+		{name: "not in the list", id: "private", wantStatus: http.StatusNotFound},
+		{name: "unknown", id: "missing", wantStatus: http.StatusNotFound},
+		// the ID that gets assigned automatically isn't accepted
+		{name: "numeric id", id: strconv.FormatUint(app.widgetByConfigID["allowed"].GetID(), 10), wantStatus: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/embed/widget/"+test.id, nil)
+			request.SetPathValue("widget", test.id)
+
+			recorder := httptest.NewRecorder()
+			app.handleEmbedRequest(recorder, request)
+
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("expected status %d, got %d", test.wantStatus, recorder.Code)
+			}
+
+			body := recorder.Body.String()
+			if test.want != "" && !strings.Contains(body, test.want) {
+				t.Errorf("expected the response to contain %q, got:\n%s", test.want, body)
+			}
+
+			if strings.Contains(body, "private widget") {
+				t.Error("expected the response not to contain any other widget")
+			}
+		})
+	}
+}
+
+func TestEmbeddableWidgetsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name: "unknown id",
+			config: `
+server:
+  embeddable-widgets: [missing]
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: html
+            id: present
+            source: <p></p>
+`,
+			wantErr: `"missing"`,
+		},
+		{
+			name: "duplicate id",
+			config: `
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: html
+            id: same
+            source: <p></p>
+  - name: Other
+    columns:
+      - size: full
+        widgets:
+          - type: html
+            id: same
+            source: <p></p>
+`,
+			wantErr: `"same"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newTestEmbeddingApp(t, test.config)
+
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("expected an error mentioning %s, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
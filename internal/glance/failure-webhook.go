@@ -0,0 +1,72 @@
+package glance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+const defaultFailureWebhookAfter = 3
+
+type failureWebhookPayload struct {
+	WidgetID uint64 `json:"widget-id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Error    string `json:"error"`
+	Failures int    `json:"failures"`
+}
+
+// Counts consecutive failed updates and notifies the webhook once they reach
+// the configured amount, the count only resets once an update succeeds so
+// that a single outage results in a single notification
+func (w *widgetBase) recordUpdateOutcome(err error) {
+	if err == nil {
+		w.consecutiveFailures = 0
+		return
+	}
+
+	w.consecutiveFailures++
+
+	after := w.FailureWebhookAfter
+	if after <= 0 {
+		after = defaultFailureWebhookAfter
+	}
+
+	if w.FailureWebhook == "" || w.consecutiveFailures != after {
+		return
+	}
+
+	payload := failureWebhookPayload{
+		WidgetID: w.ID,
+		Type:     w.Type,
+		Title:    w.Title,
+		Error:    err.Error(),
+		Failures: w.consecutiveFailures,
+	}
+
+	go func() {
+		if err := sendWebhook(w.FailureWebhook, payload); err != nil {
+			slog.Error("Failed to send failure webhook", "widget", payload.WidgetID, "error", err)
+		}
+	}()
+}
+
+func sendWebhook(webhookURL string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	response, err := defaultHTTPClient.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+
+	return nil
+}
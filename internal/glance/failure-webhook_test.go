@@ -0,0 +1,88 @@
+package glance
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFailureWebhookFiresOncePerStreak(t *testing.T) {
+	calls := make(chan failureWebhookPayload, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload failureWebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+
+		calls <- payload
+	}))
+	defer server.Close()
+
+	widget := &widgetBase{
+		ID:                  7,
+		Type:                "bilibili-videos",
+		Title:               "Videos",
+		FailureWebhook:      server.URL,
+		FailureWebhookAfter: 3,
+		cacheType:           cacheTypeDuration,
+		cacheDuration:       time.Hour,
+	}
+
+	fail := func(times int) {
+		for range times {
+			widget.canContinueUpdateAfterHandlingErr(fmt.Errorf("%w: timed out", errNoContent))
+		}
+	}
+
+	expectCalls := func(want int) []failureWebhookPayload {
+		t.Helper()
+
+		received := make([]failureWebhookPayload, 0, want)
+		for range want {
+			select {
+			case payload := <-calls:
+				received = append(received, payload)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("expected %d webhook calls, got %d", want, len(received))
+			}
+		}
+
+		select {
+		case payload := <-calls:
+			t.Fatalf("expected no more than %d webhook calls, got another one with %+v", want, payload)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		return received
+	}
+
+	fail(2)
+	expectCalls(0)
+
+	fail(5)
+	payload := expectCalls(1)[0]
+
+	want := failureWebhookPayload{
+		WidgetID: 7,
+		Type:     "bilibili-videos",
+		Title:    "Videos",
+		Error:    "failed to retrieve any content: timed out",
+		Failures: 3,
+	}
+	if payload != want {
+		t.Errorf("expected the payload %+v, got %+v", want, payload)
+	}
+
+	// partial failures don't count towards a streak and end the current one
+	widget.canContinueUpdateAfterHandlingErr(errPartialContent)
+	fail(2)
+	expectCalls(0)
+
+	widget.canContinueUpdateAfterHandlingErr(nil)
+	fail(3)
+	expectCalls(1)
+}
@@ -0,0 +1,146 @@
+package glance
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const faviconCacheDuration = 24 * time.Hour
+
+var (
+	faviconLinkPattern = regexp.MustCompile(`(?i)<link[^>]+rel=["'](?:shortcut )?icon["'][^>]*>`)
+	faviconHrefPattern = regexp.MustCompile(`(?i)href=["']([^"']+)["']`)
+)
+
+type faviconCacheEntry struct {
+	url        string
+	resolvedAt time.Time
+}
+
+// Favicons rarely change and resolving them requires fetching the site's
+// home page, so results are shared between all widgets, including failed
+// lookups which get cached as an empty URL
+var faviconCache = struct {
+	mu     sync.Mutex
+	byHost map[string]faviconCacheEntry
+}{
+	byHost: make(map[string]faviconCacheEntry),
+}
+
+func getCachedFavicon(host string) (string, bool) {
+	faviconCache.mu.Lock()
+	defer faviconCache.mu.Unlock()
+
+	entry, exists := faviconCache.byHost[host]
+	if !exists || time.Since(entry.resolvedAt) > faviconCacheDuration {
+		return "", false
+	}
+
+	return entry.url, true
+}
+
+func setCachedFavicon(host string, faviconURL string) {
+	faviconCache.mu.Lock()
+	defer faviconCache.mu.Unlock()
+
+	faviconCache.byHost[host] = faviconCacheEntry{
+		url:        faviconURL,
+		resolvedAt: time.Now(),
+	}
+}
+
+func findFaviconInPage(siteURL *url.URL) string {
+	request, _ := http.NewRequest("GET", siteURL.String(), nil)
+	setBrowserUserAgentHeader(request)
+
+	response, err := defaultHTTPClient.Do(request)
+	if err != nil {
+		return ""
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	// the link tag is in the head, no need to read the entire page
+	body, err := io.ReadAll(io.LimitReader(response.Body, 256*1024))
+	if err != nil {
+		return ""
+	}
+
+	link := faviconLinkPattern.Find(body)
+	if link == nil {
+		return ""
+	}
+
+	matches := faviconHrefPattern.FindSubmatch(link)
+	if len(matches) != 2 {
+		return ""
+	}
+
+	href, err := url.Parse(string(matches[1]))
+	if err != nil {
+		return ""
+	}
+
+	return siteURL.ResolveReference(href).String()
+}
+
+func faviconExistsAtRoot(siteURL *url.URL) (string, bool) {
+	faviconURL := siteURL.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+	request, _ := http.NewRequest("HEAD", faviconURL, nil)
+
+	response, err := defaultHTTPClient.Do(request)
+	if err != nil {
+		return "", false
+	}
+	response.Body.Close()
+
+	return faviconURL, response.StatusCode == http.StatusOK
+}
+
+// Returns the favicon URL of the site the given page belongs to or an empty
+// string if one could not be found
+func resolveFaviconURL(pageURL string) string {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil || parsedURL.Host == "" {
+		return ""
+	}
+
+	if faviconURL, cached := getCachedFavicon(parsedURL.Host); cached {
+		return faviconURL
+	}
+
+	siteURL := &url.URL{Scheme: parsedURL.Scheme, Host: parsedURL.Host, Path: "/"}
+	if siteURL.Scheme == "" {
+		siteURL.Scheme = "https"
+	}
+
+	faviconURL := findFaviconInPage(siteURL)
+	if faviconURL == "" {
+		if rootFaviconURL, exists := faviconExistsAtRoot(siteURL); exists {
+			faviconURL = rootFaviconURL
+		}
+	}
+
+	setCachedFavicon(parsedURL.Host, faviconURL)
+
+	return faviconURL
+}
+
+// Resolves the favicons of multiple pages concurrently, returning them in
+// the same order as the given page URLs
+func resolveFaviconURLs(pageURLs []string) []string {
+	job := newJob(func(pageURL string) (string, error) {
+		return resolveFaviconURL(pageURL), nil
+	}, pageURLs).withWorkers(10)
+
+	faviconURLs, _, _ := workerPoolDo(job)
+
+	return faviconURLs
+}
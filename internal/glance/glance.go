@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"hash/fnv"
 	"html/template"
 	"log"
 	"net/http"
@@ -23,32 +24,45 @@ var (
 type application struct {
 	Version          string
 	Config           config
-	ParsedThemeStyle template.HTML
-
-	slugToPage map[string]*page
-	widgetByID map[uint64]widget
+	ParsedThemeStyle template.CSS
+
+	slugToPage       map[string]*page
+	widgetByID       map[uint64]widget
+	widgetByConfigID map[string]widget
+	// the page each widget is on, for updating widgets outside of page requests
+	widgetPages map[uint64]*page
+	clicks      clickCounter
 }
 
 func newApplication(config *config) (*application, error) {
 	app := &application{
-		Version:    buildVersion,
-		Config:     *config,
-		slugToPage: make(map[string]*page),
-		widgetByID: make(map[uint64]widget),
+		Version:          buildVersion,
+		Config:           *config,
+		slugToPage:       make(map[string]*page),
+		widgetByID:       make(map[uint64]widget),
+		widgetByConfigID: make(map[string]widget),
+		widgetPages:      make(map[uint64]*page),
 	}
 
+	configureDefaultHTTPClients(config.Server.ForceHTTP1)
+
 	app.slugToPage[""] = &config.Pages[0]
 
 	providers := &widgetProviders{
-		assetResolver: app.AssetPath,
+		assetResolver:      app.AssetPath,
+		refreshMergeWindow: time.Duration(config.Server.RefreshMergeWindow),
 	}
 
-	var err error
-	app.ParsedThemeStyle, err = executeTemplateToHTML(pageThemeStyleTemplate, &app.Config.Theme)
+	themeStyle, err := executeTemplateToHTML(pageThemeStyleTemplate, &app.Config.Theme)
 	if err != nil {
 		return nil, fmt.Errorf("parsing theme style: %v", err)
 	}
 
+	// gets rendered in a style block of the templates so that the block can
+	// carry the nonce of the content security policy
+	app.ParsedThemeStyle = template.CSS(themeStyle)
+	app.Config.Document.Head = template.HTML(withNoncePlaceholders(string(app.Config.Document.Head)))
+
 	for p := range config.Pages {
 		page := &config.Pages[p]
 		page.PrimaryColumnIndex = -1
@@ -68,13 +82,21 @@ func newApplication(config *config) (*application, error) {
 
 			for w := range column.Widgets {
 				widget := column.Widgets[w]
-				app.widgetByID[widget.GetID()] = widget
+				if err := app.registerWidget(widget, page); err != nil {
+					return nil, err
+				}
 
 				widget.setProviders(providers)
 			}
 		}
 	}
 
+	for _, id := range config.Server.EmbeddableWidgets {
+		if _, exists := app.widgetByConfigID[id]; !exists {
+			return nil, fmt.Errorf("embeddable widget %q doesn't match the id of any widget", id)
+		}
+	}
+
 	config = &app.Config
 
 	config.Server.BaseURL = strings.TrimRight(config.Server.BaseURL, "/")
@@ -88,9 +110,130 @@ func newApplication(config *config) (*application, error) {
 
 	config.Branding.LogoURL = app.transformUserDefinedAssetPath(config.Branding.LogoURL)
 
+	for p := range config.Pages {
+		page := &config.Pages[p]
+
+		if page.FaviconURL == "" {
+			page.FaviconURL = config.Branding.FaviconURL
+		} else {
+			page.FaviconURL = app.transformUserDefinedAssetPath(page.FaviconURL)
+		}
+	}
+
 	return app, nil
 }
 
+// Implemented by widgets that hold other widgets, such as groups
+type containerWidget interface {
+	childWidgets() widgets
+}
+
+// Makes the widget and any widgets within it reachable by their ID
+func (a *application) registerWidget(widget widget, page *page) error {
+	a.widgetByID[widget.GetID()] = widget
+	a.widgetPages[widget.GetID()] = page
+
+	if configID := widget.getConfigID(); configID != "" {
+		if _, exists := a.widgetByConfigID[configID]; exists {
+			return fmt.Errorf("widget id %q is used more than once", configID)
+		}
+
+		a.widgetByConfigID[configID] = widget
+	}
+
+	if container, ok := widget.(containerWidget); ok {
+		for _, child := range container.childWidgets() {
+			if err := a.registerWidget(child, page); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Implemented by widgets with images worth loading before the rest of the
+// page's content, returned in the order they appear in
+type imagePreloadingWidget interface {
+	preloadImages() []preloadImage
+}
+
+// Srcset and Sizes have to match those of the image itself, otherwise the
+// browser ends up downloading it twice
+type preloadImage struct {
+	URL    string
+	Srcset string
+	Sizes  string
+}
+
+// The templates treat any attribute with "src" in its name as a single URL,
+// which would escape the spaces and commas that separate srcset candidates
+func (i preloadImage) SrcsetAttrs() template.HTMLAttr {
+	if i.Srcset == "" {
+		return ""
+	}
+
+	return template.HTMLAttr(fmt.Sprintf(
+		`imagesrcset="%s" imagesizes="%s"`,
+		template.HTMLEscapeString(i.Srcset),
+		template.HTMLEscapeString(i.Sizes),
+	))
+}
+
+// Returns up to PreloadCount images from the page's widgets, in the order
+// they appear on the page
+func (p *page) PreloadImages() []preloadImage {
+	if p.PreloadCount <= 0 {
+		return nil
+	}
+
+	// the widgets may be in the middle of updating, in which case
+	// waiting for them would only delay the page
+	if !p.mu.TryLock() {
+		return nil
+	}
+	defer p.mu.Unlock()
+
+	images := make([]preloadImage, 0, p.PreloadCount)
+
+	for c := range p.Columns {
+		for _, widget := range p.Columns[c].Widgets {
+			preloading, ok := widget.(imagePreloadingWidget)
+			if !ok {
+				continue
+			}
+
+			for _, image := range preloading.preloadImages() {
+				if len(images) == p.PreloadCount {
+					return images
+				}
+
+				images = append(images, image)
+			}
+		}
+	}
+
+	return images
+}
+
+// Implemented by widgets that can leave out items already shown by
+// widgets that come before them on the same page
+type pageDedupingWidget interface {
+	setPageSeenSet(seen map[string]struct{})
+}
+
+// Gives every widget on the page that dedups across it the same set, which
+// gets filled in the order the widgets render in
+func (p *page) setSeenSet(seen map[string]struct{}) {
+	for c := range p.Columns {
+		for _, widget := range p.Columns[c].Widgets {
+			if deduping, ok := widget.(pageDedupingWidget); ok {
+				deduping.setPageSeenSet(seen)
+			}
+		}
+	}
+}
+
 func (p *page) updateOutdatedWidgets() {
 	now := time.Now()
 
@@ -108,7 +251,7 @@ func (p *page) updateOutdatedWidgets() {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				widget.update(context)
+				updateWidget(context, widget)
 			}()
 		}
 	}
@@ -116,6 +259,51 @@ func (p *page) updateOutdatedWidgets() {
 	wg.Wait()
 }
 
+// Starts the first update of every widget in the background so that their
+// content is usually ready by the time the first page gets opened. Pages
+// opened before then wait for the updates to finish, same as they would if
+// the widgets were updating because of the page being opened
+func (a *application) warmUpWidgets(concurrency int) {
+	slots := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+
+	for p := range a.Config.Pages {
+		page := &a.Config.Pages[p]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			page.mu.Lock()
+			defer page.mu.Unlock()
+
+			now := time.Now()
+			var pageWg sync.WaitGroup
+
+			for c := range page.Columns {
+				for _, widget := range page.Columns[c].Widgets {
+					if !widget.requiresUpdate(&now) {
+						continue
+					}
+
+					slots <- struct{}{}
+					pageWg.Add(1)
+
+					go func() {
+						defer pageWg.Done()
+						defer func() { <-slots }()
+						updateWidget(context.Background(), widget)
+					}()
+				}
+			}
+
+			pageWg.Wait()
+		}()
+	}
+
+	wg.Wait()
+}
+
 func (a *application) transformUserDefinedAssetPath(path string) string {
 	if strings.HasPrefix(path, "/assets/") {
 		return a.Config.Server.BaseURL + path
@@ -125,8 +313,17 @@ func (a *application) transformUserDefinedAssetPath(path string) string {
 }
 
 type pageTemplateData struct {
-	App  *application
-	Page *page
+	App   *application
+	Page  *page
+	Nonce string
+}
+
+func (d pageTemplateData) DocumentHead() template.HTML {
+	return template.HTML(withContentSecurityPolicyNonce(string(d.App.Config.Document.Head), d.Nonce))
+}
+
+func (d pageTemplateData) NoncePlaceholder() string {
+	return contentSecurityPolicyNoncePlaceholder
 }
 
 func (a *application) handlePageRequest(w http.ResponseWriter, r *http.Request) {
@@ -138,8 +335,9 @@ func (a *application) handlePageRequest(w http.ResponseWriter, r *http.Request)
 	}
 
 	pageData := pageTemplateData{
-		Page: page,
-		App:  a,
+		Page:  page,
+		App:   a,
+		Nonce: a.setContentSecurityPolicy(w),
 	}
 
 	var responseBytes bytes.Buffer
@@ -173,7 +371,9 @@ func (a *application) handlePageContentRequest(w http.ResponseWriter, r *http.Re
 		defer page.mu.Unlock()
 
 		page.updateOutdatedWidgets()
+		page.setSeenSet(make(map[string]struct{}))
 		err = pageContentTemplate.Execute(&responseBytes, pageData)
+		page.setSeenSet(nil)
 	}()
 
 	if err != nil {
@@ -182,9 +382,39 @@ func (a *application) handlePageContentRequest(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// the content is made up of what every widget rendered, so when none of
+	// them rendered anything different there's no need to send it again,
+	// which spares displays that keep polling the page from repainting
+	etag := pageContentETag(responseBytes.Bytes())
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	w.Write(responseBytes.Bytes())
 }
 
+func pageContentETag(content []byte) string {
+	hash := fnv.New64a()
+	hash.Write(content)
+
+	return `"` + strconv.FormatUint(hash.Sum64(), 36) + `"`
+}
+
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (a *application) handleNotFound(w http.ResponseWriter, _ *http.Request) {
 	// TODO: add proper not found page
 	w.WriteHeader(http.StatusNotFound)
@@ -224,6 +454,14 @@ func (a *application) server() (func() error, func() error) {
 
 	mux.HandleFunc("GET /api/pages/{page}/content/{$}", a.handlePageContentRequest)
 	mux.HandleFunc("/api/widgets/{widget}/{path...}", a.handleWidgetRequest)
+	mux.HandleFunc("POST /api/click", a.handleClickRequest)
+	mux.HandleFunc("GET /feed/widget/{widget}", a.handleWidgetFeedRequest)
+	mux.HandleFunc("GET /embed/widget/{widget}", a.handleEmbedRequest)
+
+	if a.Config.Server.Debug {
+		mux.HandleFunc("GET /api/metrics", a.handleMetricsRequest)
+		mux.HandleFunc("GET /debug/widget/{widget}/feed/{feed}", a.handleDebugFeedRequest)
+	}
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
@@ -242,7 +480,7 @@ func (a *application) server() (func() error, func() error) {
 
 	server := http.Server{
 		Addr:    fmt.Sprintf("%s:%d", a.Config.Server.Host, a.Config.Server.Port),
-		Handler: mux,
+		Handler: a.Config.Auth.middleware(mux),
 	}
 
 	start := func() error {
@@ -254,6 +492,10 @@ func (a *application) server() (func() error, func() error) {
 			absAssetsPath,
 		)
 
+		if a.Config.Server.Warmup {
+			go a.warmUpWidgets(a.Config.Server.WarmupConcurrency)
+		}
+
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			return err
 		}
@@ -0,0 +1,411 @@
+package glance
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEtagMatches(t *testing.T) {
+	etag := pageContentETag([]byte("content"))
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        bool
+	}{
+		{"no header", "", false},
+		{"same", etag, true},
+		{"weak", "W/" + etag, true},
+		{"one of many", `"other", ` + etag, true},
+		{"wildcard", "*", true},
+		{"different", pageContentETag([]byte("other content")), false},
+		{"unquoted", etag[1 : len(etag)-1], false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := etagMatches(test.ifNoneMatch, etag); got != test.want {
+				t.Errorf("etagMatches(%q, %q): expected %v, got %v", test.ifNoneMatch, etag, test.want, got)
+			}
+		})
+	}
+}
+
+func TestPageContentETag(t *testing.T) {
+	if pageContentETag([]byte("content")) != pageContentETag([]byte("content")) {
+		t.Error("expected the same content to have the same etag")
+	}
+
+	if pageContentETag([]byte("content")) == pageContentETag([]byte("changed content")) {
+		t.Error("expected different content to have different etags")
+	}
+}
+
+func TestPageContentRequestNotModified(t *testing.T) {
+	app := &application{slugToPage: map[string]*page{"home": {Title: "Home", Slug: "home"}}}
+
+	request := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/pages/home/content/", nil)
+		r.SetPathValue("page", "home")
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		recorder := httptest.NewRecorder()
+		app.handlePageContentRequest(recorder, r)
+
+		return recorder
+	}
+
+	first := request("")
+	etag := first.Header().Get("ETag")
+
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected a 200 response with an etag, got %d and %q", first.Code, etag)
+	}
+
+	if second := request(etag); second.Code != http.StatusNotModified || second.Body.Len() != 0 {
+		t.Errorf("expected an empty 304 response, got %d with %d bytes", second.Code, second.Body.Len())
+	}
+
+	if third := request(`"stale"`); third.Code != http.StatusOK || third.Body.String() != first.Body.String() {
+		t.Errorf("expected the content again for a stale etag, got %d", third.Code)
+	}
+}
+
+var preloadLinkPattern = regexp.MustCompile(`<link rel="preload" as="image" href="([^"]*)"`)
+
+func TestPagePreloadImages(t *testing.T) {
+	tests := []struct {
+		name         string
+		preloadCount int
+		want         []string
+	}{
+		{name: "disabled"},
+		{name: "fewer than available", preloadCount: 3, want: []string{"/1.jpg", "/2.jpg", "/3.jpg"}},
+		{name: "more than available", preloadCount: 10, want: []string{"/1.jpg", "/2.jpg", "/3.jpg", "/4.jpg"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := newConfigFromYAML([]byte(fmt.Sprintf(`
+pages:
+  - name: Home
+    preload-count: %d
+    columns:
+      - size: full
+        widgets:
+          - type: bilibili-videos
+            rsshuburls: [https://rsshub.example/bilibili/user/video/1]
+          - type: group
+            widgets:
+              - type: bilibili-videos
+                rsshuburls: [https://rsshub.example/bilibili/user/video/2]
+`, test.preloadCount)))
+			if err != nil {
+				t.Fatalf("parsing config: %v", err)
+			}
+
+			app, err := newApplication(config)
+			if err != nil {
+				t.Fatalf("creating application: %v", err)
+			}
+
+			widgets := app.slugToPage["home"].Columns[0].Widgets
+			withThumbnails := func(widget *bilibiliVideosWidget, thumbnails ...string) {
+				widget.ContentAvailable = true
+				for i, thumbnail := range thumbnails {
+					widget.Videos = append(widget.Videos, bilibiliVideo{
+						Url:          fmt.Sprintf("https://www.bilibili.com/video/%s", thumbnail),
+						ThumbnailUrl: thumbnail,
+						TimePosted:   time.Now().Add(-time.Duration(i) * time.Minute),
+					})
+				}
+			}
+
+			// embedded thumbnails have nothing to preload
+			withThumbnails(widgets[0].(*bilibiliVideosWidget), "/1.jpg", "data:image/png;base64,iVBORw0KGgo=", "/2.jpg")
+			withThumbnails(widgets[1].(*groupWidget).Widgets[0].(*bilibiliVideosWidget), "/3.jpg", "/4.jpg")
+
+			request := httptest.NewRequest("GET", "/", nil)
+			request.SetPathValue("page", "home")
+			recorder := httptest.NewRecorder()
+			app.handlePageRequest(recorder, request)
+
+			var got []string
+			for _, match := range preloadLinkPattern.FindAllStringSubmatch(recorder.Body.String(), -1) {
+				got = append(got, match[1])
+			}
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("expected the preloaded images %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestPageDedupScope(t *testing.T) {
+	config, err := newConfigFromYAML([]byte(`
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: bilibili-videos
+            title: First
+            dedup-scope: page
+            rsshuburls: [https://rsshub.example/bilibili/user/video/1]
+          - type: bilibili-videos
+            title: Second
+            dedup-scope: page
+            rsshuburls: [https://rsshub.example/bilibili/user/video/2]
+          - type: bilibili-videos
+            title: Third
+            rsshuburls: [https://rsshub.example/bilibili/user/video/3]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	app, err := newApplication(config)
+	if err != nil {
+		t.Fatalf("creating application: %v", err)
+	}
+
+	for i, widget := range app.slugToPage["home"].Columns[0].Widgets {
+		videos := widget.(*bilibiliVideosWidget)
+		videos.ContentAvailable = true
+		videos.nextUpdate = time.Now().Add(time.Hour)
+		videos.Videos = bilibiliVideoList{
+			{Title: "Shared video", Url: "https://www.bilibili.com/video/BVshared", TimePosted: time.Now()},
+			{Title: fmt.Sprintf("Own video %d", i+1), Url: fmt.Sprintf("https://www.bilibili.com/video/BV%d", i+1), TimePosted: time.Now()},
+		}
+	}
+
+	// the seen videos are forgotten between requests
+	for range 2 {
+		request := httptest.NewRequest("GET", "/api/pages/home/content/", nil)
+		request.SetPathValue("page", "home")
+		recorder := httptest.NewRecorder()
+		app.handlePageContentRequest(recorder, request)
+
+		body := recorder.Body.String()
+		widgets := strings.Split(body, `<div class="widget widget-type-bilibili-videos">`)[1:]
+		if len(widgets) != 3 {
+			t.Fatalf("expected 3 widgets, got %d:\n%s", len(widgets), body)
+		}
+
+		// the third widget doesn't dedup across the page
+		for i, wantShared := range []bool{true, false, true} {
+			if strings.Contains(widgets[i], "BVshared") != wantShared {
+				t.Errorf("widget %d: expected the shared video to be shown: %t", i+1, wantShared)
+			}
+
+			if !strings.Contains(widgets[i], fmt.Sprintf("Own video %d", i+1)) {
+				t.Errorf("widget %d: expected its own video to be shown", i+1)
+			}
+		}
+	}
+}
+
+func TestWarmUpWidgets(t *testing.T) {
+	var active, peak, requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		current := active.Add(1)
+		defer active.Add(-1)
+
+		for {
+			highest := peak.Load()
+			if current <= highest || peak.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("BV"+strings.Trim(r.URL.Path, "/"), "Video", time.Now()))))
+	}))
+	t.Cleanup(server.Close)
+
+	config, err := newConfigFromYAML([]byte(`
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: bilibili-videos
+            rsshuburls: [` + server.URL + `/1]
+          - type: bilibili-videos
+            rsshuburls: [` + server.URL + `/2]
+  - name: Other
+    columns:
+      - size: full
+        widgets:
+          - type: bilibili-videos
+            rsshuburls: [` + server.URL + `/3]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	app, err := newApplication(config)
+	if err != nil {
+		t.Fatalf("creating application: %v", err)
+	}
+
+	app.warmUpWidgets(2)
+
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected 3 feed requests, got %d", got)
+	}
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("expected at most 2 concurrent updates, got %d", got)
+	}
+
+	now := time.Now()
+	for _, slug := range []string{"home", "other"} {
+		for _, widget := range app.slugToPage[slug].Columns[0].Widgets {
+			videos := widget.(*bilibiliVideosWidget)
+
+			if len(videos.Videos) != 1 {
+				t.Errorf("expected the widget on %s to have 1 video, got %d", slug, len(videos.Videos))
+			}
+
+			if videos.requiresUpdate(&now) {
+				t.Errorf("expected the widget on %s to not require another update", slug)
+			}
+		}
+	}
+}
+
+func TestPagePreloadImageSrcset(t *testing.T) {
+	config, err := newConfigFromYAML([]byte(`
+pages:
+  - name: Home
+    preload-count: 2
+    columns:
+      - size: full
+        widgets:
+          - type: bilibili-videos
+            rsshuburls: [https://rsshub.example/bilibili/user/video/1]
+          - type: bilibili-videos
+            style: vertical-list
+            rsshuburls: [https://rsshub.example/bilibili/user/video/2]
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	app, err := newApplication(config)
+	if err != nil {
+		t.Fatalf("creating application: %v", err)
+	}
+
+	for i, widget := range app.slugToPage["home"].Columns[0].Widgets {
+		videos := widget.(*bilibiliVideosWidget)
+		videos.ContentAvailable = true
+		videos.Videos = bilibiliVideoList{{
+			Url:             fmt.Sprintf("https://www.bilibili.com/video/BV%d", i+1),
+			ThumbnailUrl:    fmt.Sprintf("/%d.jpg", i+1),
+			ThumbnailSrcset: fmt.Sprintf("/%[1]d.jpg?w=320 320w, /%[1]d.jpg?w=640 640w", i+1),
+			ThumbnailSizes:  "(max-width: 550px) 100vw, 320px",
+			TimePosted:      time.Now(),
+		}}
+	}
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.SetPathValue("page", "home")
+	recorder := httptest.NewRecorder()
+	app.handlePageRequest(recorder, request)
+	body := recorder.Body.String()
+
+	want := `<link rel="preload" as="image" href="/1.jpg" imagesrcset="/1.jpg?w=320 320w, /1.jpg?w=640 640w" imagesizes="(max-width: 550px) 100vw, 320px">`
+	if !strings.Contains(body, want) {
+		t.Errorf("expected the preload link to match the card's thumbnail: %s", want)
+	}
+
+	// the thumbnails of the list are shown without a srcset
+	if want := `<link rel="preload" as="image" href="/2.jpg">`; !strings.Contains(body, want) {
+		t.Errorf("expected the preload link to not have a srcset: %s", want)
+	}
+}
+
+func TestPageDocumentTitleAndFavicon(t *testing.T) {
+	config, err := newConfigFromYAML([]byte(`
+server:
+  embeddable-widgets: [videos]
+pages:
+  - name: Home
+    document-title: Videos dashboard
+    favicon-url: /assets/videos.png
+    columns:
+      - size: full
+        widgets:
+          - type: html
+            id: videos
+            source: <p>videos</p>
+  - name: Other
+    columns:
+      - size: full
+        widgets:
+          - type: html
+            source: <p>other</p>
+`))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+
+	app, err := newApplication(config)
+	if err != nil {
+		t.Fatalf("creating application: %v", err)
+	}
+
+	tests := []struct {
+		slug        string
+		wantTitle   string
+		wantFavicon string
+	}{
+		{slug: "home", wantTitle: "Videos dashboard", wantFavicon: "/assets/videos.png"},
+		// pages without their own fall back to the page's name and the branding's favicon
+		{slug: "other", wantTitle: "Other", wantFavicon: app.Config.Branding.FaviconURL},
+	}
+
+	for _, test := range tests {
+		t.Run(test.slug, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/"+test.slug, nil)
+			request.SetPathValue("page", test.slug)
+			recorder := httptest.NewRecorder()
+			app.handlePageRequest(recorder, request)
+			body := recorder.Body.String()
+
+			if want := "<title>" + test.wantTitle + "</title>"; !strings.Contains(body, want) {
+				t.Errorf("expected the head to contain %s", want)
+			}
+
+			if want := `<link rel="icon" type="image/png" href="` + test.wantFavicon + `" />`; !strings.Contains(body, want) {
+				t.Errorf("expected the head to contain %s", want)
+			}
+		})
+	}
+
+	// embedded widgets use the title and favicon of their page
+	request := httptest.NewRequest("GET", "/embed/widget/videos", nil)
+	request.SetPathValue("widget", "videos")
+	recorder := httptest.NewRecorder()
+	app.handleEmbedRequest(recorder, request)
+	body := recorder.Body.String()
+
+	for _, want := range []string{"<title>Videos dashboard</title>", `<link rel="icon" type="image/png" href="/assets/videos.png" />`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the embedded widget's head to contain %s", want)
+		}
+	}
+}
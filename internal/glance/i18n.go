@@ -0,0 +1,89 @@
+package glance
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const defaultWidgetLocale = "en"
+
+// Messages shown by every widget, individual ones can be overridden through
+// the messages property of a widget
+var widgetMessageCatalog = map[string]map[string]string{
+	"en": {
+		"error":           "ERROR",
+		"no-error-info":   "No error information provided",
+		"no-content":      "failed to retrieve any content",
+		"partial-content": "failed to retrieve some of the content",
+		"stale":           "showing content from an earlier update",
+	},
+	"zh": {
+		"error":           "错误",
+		"no-error-info":   "没有可用的错误信息",
+		"no-content":      "未能获取任何内容",
+		"partial-content": "未能获取部分内容",
+		"stale":           "显示的是之前更新的内容",
+	},
+}
+
+func (w *widgetBase) validateLocale() error {
+	if w.Locale == "" {
+		return nil
+	}
+
+	if _, exists := widgetMessageCatalog[w.Locale]; !exists {
+		return fmt.Errorf(
+			"unsupported locale %q, must be one of: %s",
+			w.Locale,
+			strings.Join(slices.Sorted(maps.Keys(widgetMessageCatalog)), ", "),
+		)
+	}
+
+	return nil
+}
+
+func (w *widgetBase) Message(key string) string {
+	if message, exists := w.Messages[key]; exists {
+		return message
+	}
+
+	if message, exists := widgetMessageCatalog[w.Locale][key]; exists {
+		return message
+	}
+
+	return widgetMessageCatalog[defaultWidgetLocale][key]
+}
+
+func (w *widgetBase) ErrorMessage() string {
+	return w.localizeErr(w.Error)
+}
+
+func (w *widgetBase) NoticeMessage() string {
+	return w.localizeErr(w.Notice)
+}
+
+// Replaces the generic part of content errors with its localized version
+// while keeping the details that follow it
+func (w *widgetBase) localizeErr(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	message := err.Error()
+
+	for key, generic := range map[string]error{
+		"no-content":      errNoContent,
+		"partial-content": errPartialContent,
+	} {
+		if errors.Is(err, generic) {
+			if details, found := strings.CutPrefix(message, generic.Error()); found {
+				return w.Message(key) + details
+			}
+		}
+	}
+
+	return message
+}
@@ -0,0 +1,103 @@
+package glance
+
+import (
+	"fmt"
+	"html"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWidgetMessageCatalogIsComplete(t *testing.T) {
+	keys := make(map[string]struct{})
+	for _, messages := range widgetMessageCatalog {
+		for key := range messages {
+			keys[key] = struct{}{}
+		}
+	}
+
+	for locale, messages := range widgetMessageCatalog {
+		for _, key := range slices.Sorted(maps.Keys(keys)) {
+			if messages[key] == "" {
+				t.Errorf("expected locale %s to define the %s message", locale, key)
+			}
+		}
+	}
+}
+
+func TestWidgetLocaleValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		widget  string
+		wantErr bool
+	}{
+		{name: "default", widget: "- type: html\n"},
+		{name: "supported", widget: "- type: html\n  locale: zh\n"},
+		{name: "unsupported", widget: "- type: html\n  locale: fr\n", wantErr: true},
+		{name: "unsupported in a container", widget: "- type: group\n  widgets:\n    - type: html\n      locale: fr\n", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := "pages:\n  - name: Home\n    columns:\n      - size: full\n        widgets:\n"
+			for _, line := range strings.SplitAfter(test.widget, "\n") {
+				if line != "" {
+					config += "          " + line
+				}
+			}
+
+			_, err := newConfigFromYAML([]byte(config))
+
+			if !test.wantErr {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), `"fr"`) || !strings.Contains(err.Error(), "en, zh") {
+				t.Errorf("expected an error listing the supported locales, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWidgetMessagesFollowLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "default", config: "", want: "failed to retrieve any content"},
+		{name: "chinese", config: "locale: zh\n", want: "未能获取任何内容"},
+		{name: "overridden", config: "locale: zh\nmessages:\n  no-content: 暂时没有内容\n", want: "暂时没有内容"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\n"+test.config)
+			widget.withError(fmt.Errorf("%w: timed out", errNoContent))
+
+			rendered := string(widget.Render())
+			if !strings.Contains(rendered, test.want+": timed out") {
+				t.Errorf("expected the markup to contain %q, got:\n%s", test.want, rendered)
+			}
+		})
+	}
+}
+
+func TestWidgetStaleMessageFollowsLocale(t *testing.T) {
+	for locale, messages := range widgetMessageCatalog {
+		t.Run(locale, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\nlocale: "+locale+"\n")
+			widget.ContentAvailable = true
+			widget.withError(errNoContent)
+
+			want := messages["stale"] + ": " + messages["no-content"]
+			if rendered := html.UnescapeString(string(widget.Render())); !strings.Contains(rendered, want) {
+				t.Errorf("expected the markup to contain %q, got:\n%s", want, rendered)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package glance
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type widgetMetrics struct {
+	mu                 sync.Mutex
+	lastUpdate         time.Time
+	lastUpdateDuration time.Duration
+	feedCount          int
+	peakConcurrency    int64
+	concurrency        concurrencyTracker
+	feedHealth         map[string]*feedHealth
+}
+
+type widgetMetricsSnapshot struct {
+	ID                   uint64               `json:"id"`
+	Type                 string               `json:"type"`
+	Title                string               `json:"title"`
+	LastUpdate           time.Time            `json:"last-update"`
+	LastUpdateDurationMs int64                `json:"last-update-duration-ms"`
+	FeedCount            int                  `json:"feed-count"`
+	PeakConcurrency      int64                `json:"peak-concurrent-requests"`
+	Feeds                []feedHealthSnapshot `json:"feeds,omitempty"`
+}
+
+// How many of the most recent fetches of a feed its health is based on
+const feedHealthWindow = 20
+
+type feedHealth struct {
+	outcomes [feedHealthWindow]bool
+	recorded int
+	next     int
+}
+
+func (h *feedHealth) record(succeeded bool) {
+	h.outcomes[h.next] = succeeded
+	h.next = (h.next + 1) % feedHealthWindow
+
+	if h.recorded < feedHealthWindow {
+		h.recorded++
+	}
+}
+
+// Returns the percentage of recent fetches that succeeded
+func (h *feedHealth) percentage() float64 {
+	if h.recorded == 0 {
+		return 0
+	}
+
+	succeeded := 0
+	for i := range h.recorded {
+		if h.outcomes[i] {
+			succeeded++
+		}
+	}
+
+	return float64(succeeded) * 100 / float64(h.recorded)
+}
+
+type feedHealthSnapshot struct {
+	URL     string  `json:"url"`
+	Health  float64 `json:"health"`
+	Fetches int     `json:"fetches"`
+}
+
+func (w *widgetBase) recordFeedOutcome(url string, err error) {
+	w.metrics.mu.Lock()
+	defer w.metrics.mu.Unlock()
+
+	if w.metrics.feedHealth == nil {
+		w.metrics.feedHealth = make(map[string]*feedHealth)
+	}
+
+	health, exists := w.metrics.feedHealth[url]
+	if !exists {
+		health = &feedHealth{}
+		w.metrics.feedHealth[url] = health
+	}
+
+	health.record(err == nil)
+}
+
+func (w *widgetBase) feedHealthPercentage(url string) (float64, bool) {
+	w.metrics.mu.Lock()
+	defer w.metrics.mu.Unlock()
+
+	health, exists := w.metrics.feedHealth[url]
+	if !exists {
+		return 0, false
+	}
+
+	return health.percentage(), true
+}
+
+// Keeps track of the highest number of tasks that were running at once
+type concurrencyTracker struct {
+	active atomic.Int64
+	peak   atomic.Int64
+}
+
+func (t *concurrencyTracker) begin() {
+	active := t.active.Add(1)
+
+	for {
+		peak := t.peak.Load()
+		if active <= peak || t.peak.CompareAndSwap(peak, active) {
+			return
+		}
+	}
+}
+
+func (t *concurrencyTracker) end() {
+	t.active.Add(-1)
+}
+
+// Returns the peak since the last call and starts tracking a new one
+func (t *concurrencyTracker) takePeak() int64 {
+	return t.peak.Swap(0)
+}
+
+func (w *widgetBase) recordUpdateMetrics(startedAt time.Time, feedCount int) {
+	w.metrics.mu.Lock()
+	defer w.metrics.mu.Unlock()
+
+	w.metrics.lastUpdate = startedAt
+	w.metrics.lastUpdateDuration = time.Since(startedAt)
+	w.metrics.feedCount = feedCount
+	w.metrics.peakConcurrency = w.metrics.concurrency.takePeak()
+}
+
+func (w *widgetBase) getMetrics() widgetMetricsSnapshot {
+	w.metrics.mu.Lock()
+	defer w.metrics.mu.Unlock()
+
+	var feeds []feedHealthSnapshot
+	for url, health := range w.metrics.feedHealth {
+		feeds = append(feeds, feedHealthSnapshot{
+			URL:     redactFeedUrl(url),
+			Health:  health.percentage(),
+			Fetches: health.recorded,
+		})
+	}
+
+	slices.SortFunc(feeds, func(a, b feedHealthSnapshot) int {
+		return cmp.Compare(a.URL, b.URL)
+	})
+
+	return widgetMetricsSnapshot{
+		ID:                   w.ID,
+		Type:                 w.Type,
+		Title:                w.Title,
+		LastUpdate:           w.metrics.lastUpdate,
+		LastUpdateDurationMs: w.metrics.lastUpdateDuration.Milliseconds(),
+		FeedCount:            w.metrics.feedCount,
+		PeakConcurrency:      w.metrics.peakConcurrency,
+		Feeds:                feeds,
+	}
+}
+
+// Feed URLs often carry access keys in their query, e.g. RSSHub's key=
+func redactFeedUrl(rawUrl string) string {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return ""
+	}
+
+	parsedUrl.User = nil
+	parsedUrl.RawQuery = ""
+	parsedUrl.Fragment = ""
+
+	return parsedUrl.String()
+}
+
+func (a *application) handleMetricsRequest(w http.ResponseWriter, _ *http.Request) {
+	snapshots := make([]widgetMetricsSnapshot, 0, len(a.widgetByID))
+
+	for _, widget := range a.widgetByID {
+		snapshot := widget.getMetrics()
+
+		// widgets that fetch nothing have nothing worth reporting
+		if snapshot.LastUpdate.IsZero() {
+			continue
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	slices.SortFunc(snapshots, func(a, b widgetMetricsSnapshot) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Widgets []widgetMetricsSnapshot `json:"widgets"`
+	}{
+		Widgets: snapshots,
+	})
+}
@@ -0,0 +1,160 @@
+package glance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBilibiliVideosUpdateRecordsMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("BV1", "Video", time.Now()))))
+	}))
+	defer server.Close()
+
+	widget := newTestBilibiliVideosWidget(t, "rsshuburls: ["+server.URL+"/a, "+server.URL+"/b]\n")
+
+	if metrics := widget.getMetrics(); !metrics.LastUpdate.IsZero() || metrics.LastUpdateDurationMs != 0 {
+		t.Fatalf("expected no metrics before the first update, got %+v", metrics)
+	}
+
+	startedAt := time.Now()
+	widget.update(context.Background())
+
+	metrics := widget.getMetrics()
+
+	if metrics.LastUpdate.Before(startedAt) {
+		t.Errorf("expected the time of the update to be recorded, got %v", metrics.LastUpdate)
+	}
+
+	if metrics.LastUpdateDurationMs < 10 {
+		t.Errorf("expected the update to have taken at least 10ms, got %dms", metrics.LastUpdateDurationMs)
+	}
+
+	if metrics.FeedCount != 2 {
+		t.Errorf("expected 2 feeds, got %d", metrics.FeedCount)
+	}
+
+	if metrics.PeakConcurrency < 1 {
+		t.Errorf("expected at least 1 concurrent request, got %d", metrics.PeakConcurrency)
+	}
+}
+
+func TestBilibiliVideosPeakConcurrency(t *testing.T) {
+	const workers = 3
+
+	var mu sync.Mutex
+	arrived := 0
+	allArrived := make(chan struct{})
+
+	// holds the first requests until as many as there are workers are in flight
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrived++
+		if arrived == workers {
+			close(allArrived)
+		}
+		mu.Unlock()
+
+		select {
+		case <-allArrived:
+		case <-time.After(5 * time.Second):
+		}
+
+		w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("BV1", "Video", time.Now()))))
+	}))
+	defer server.Close()
+
+	config := "rsshuburls:\n"
+	for i := range 6 {
+		config += fmt.Sprintf("  - %s/%d\n", server.URL, i)
+	}
+
+	widget := newTestBilibiliVideosWidget(t, config)
+	widget.concurrencyLimit = &adaptiveConcurrencyLimit{limit: workers}
+
+	widget.update(context.Background())
+
+	if peak := widget.getMetrics().PeakConcurrency; peak != workers {
+		t.Errorf("expected a peak of %d concurrent requests, got %d", workers, peak)
+	}
+
+	// the peak is that of the last update rather than of all of them
+	widget.concurrencyLimit = &adaptiveConcurrencyLimit{limit: 1}
+	widget.update(context.Background())
+
+	if peak := widget.getMetrics().PeakConcurrency; peak != 1 {
+		t.Errorf("expected a peak of 1 concurrent request, got %d", peak)
+	}
+}
+
+func TestBilibiliVideosFeedHealth(t *testing.T) {
+	var requests atomic.Int32
+
+	// the flaky feed fails every other fetch, starting with the second one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && requests.Add(1)%2 == 0 {
+			http.Error(w, "rsshub is down", http.StatusBadGateway)
+			return
+		}
+
+		w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("BV1", "Video", time.Now()))))
+	}))
+	defer server.Close()
+
+	widget := newTestBilibiliVideosWidget(t, "rsshuburls:\n  - "+server.URL+"/flaky?key=secret\n  - "+server.URL+"/stable\n")
+
+	for range 4 {
+		widget.update(context.Background())
+	}
+
+	want := []feedHealthSnapshot{
+		{URL: server.URL + "/flaky", Health: 50, Fetches: 4},
+		{URL: server.URL + "/stable", Health: 100, Fetches: 4},
+	}
+
+	if got := widget.getMetrics().Feeds; !slices.Equal(got, want) {
+		t.Errorf("expected the feed health %+v, got %+v", want, got)
+	}
+}
+
+func TestFeedHealthPercentage(t *testing.T) {
+	var health feedHealth
+
+	if got := health.percentage(); got != 0 {
+		t.Errorf("expected 0 without any fetches, got %v", got)
+	}
+
+	for _, succeeded := range []bool{true, false, true, true} {
+		health.record(succeeded)
+	}
+
+	if got := health.percentage(); got != 75 {
+		t.Errorf("expected 75, got %v", got)
+	}
+
+	// only the most recent fetches count once there are more than the window
+	for i := range feedHealthWindow {
+		health.record(i%4 != 0)
+	}
+
+	if got := health.percentage(); got != 75 || health.recorded != feedHealthWindow {
+		t.Errorf("expected 75 over %d fetches, got %v over %d", feedHealthWindow, got, health.recorded)
+	}
+
+	// leaves 8 of the 15 successes in the window
+	for range feedHealthWindow / 2 {
+		health.record(false)
+	}
+
+	if got := health.percentage(); got != 40 {
+		t.Errorf("expected 40, got %v", got)
+	}
+}
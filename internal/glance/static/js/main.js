@@ -8,7 +8,15 @@ async function fetchPageContent(pageData) {
     const response = await fetch(`${pageData.baseURL}/api/pages/${pageData.slug}/content/`);
     const content = await response.text();
 
-    return content;
+    return withNonce(content, pageData);
+}
+
+// the inline blocks of HTML from the config are marked with a placeholder
+// that stands in for the nonce of the page they end up on
+function withNonce(content, pageData) {
+    const attribute = ` nonce="${pageData.noncePlaceholder}"`;
+
+    return content.replaceAll(attribute, pageData.nonce ? ` nonce="${pageData.nonce}"` : "");
 }
 
 function setupCarousels() {
@@ -246,6 +254,37 @@ function setupDynamicRelativeTime() {
     });
 }
 
+function formatRefreshCountdown(seconds) {
+    if (seconds <= 0) return "due";
+
+    const hours = Math.floor(seconds / 3600);
+    const minutes = Math.floor((seconds % 3600) / 60);
+
+    if (hours > 0) return `${hours}h ${minutes}m`;
+
+    return `${minutes}:${String(seconds % 60).padStart(2, "0")}`;
+}
+
+function setupRefreshCountdowns() {
+    const elements = document.querySelectorAll("[data-next-refresh]");
+
+    if (elements.length == 0) {
+        return;
+    }
+
+    const updateCountdowns = () => {
+        const now = Math.floor(Date.now() / 1000);
+
+        for (let i = 0; i < elements.length; i++) {
+            const element = elements[i];
+            element.textContent = formatRefreshCountdown(Number(element.dataset.nextRefresh) - now);
+        }
+    };
+
+    updateCountdowns();
+    setInterval(updateCountdowns, 1000);
+}
+
 function setupGroups() {
     const groups = document.getElementsByClassName("widget-type-group");
 
@@ -337,7 +376,199 @@ function setupLazyImages() {
     });
 }
 
+function setupClickTracking() {
+    const containers = document.querySelectorAll("[data-track-clicks]");
+
+    const recordClick = (container, event) => {
+        const link = event.target.closest("a[href]");
+
+        if (link === null || !container.contains(link)) {
+            return;
+        }
+
+        const body = JSON.stringify({
+            "widget-id": Number(container.dataset.trackClicks),
+            "url": link.href,
+        });
+
+        navigator.sendBeacon(`${pageData.baseURL}/api/click`, new Blob([body], { type: "application/json" }));
+    };
+
+    for (let i = 0; i < containers.length; i++) {
+        const container = containers[i];
+
+        container.addEventListener("click", (event) => recordClick(container, event));
+        // middle clicks open links in a new tab without triggering a click event
+        container.addEventListener("auxclick", (event) => {
+            if (event.button === 1) recordClick(container, event);
+        });
+    }
+}
+
+function setupAppDeepLinks() {
+    // desktops rarely have the apps installed, so links are left as they are
+    if (!window.matchMedia("(pointer: coarse)").matches) {
+        return;
+    }
+
+    const links = document.querySelectorAll("a[data-app-href]");
+
+    for (let i = 0; i < links.length; i++) {
+        const link = links[i];
+
+        link.addEventListener("click", (event) => {
+            event.preventDefault();
+
+            // if the app opens the page gets hidden, otherwise it's not installed
+            // and the website gets opened instead
+            const fallbackTimeout = setTimeout(() => {
+                window.location.href = link.href;
+            }, 1000);
+
+            document.addEventListener("visibilitychange", () => {
+                if (document.hidden) clearTimeout(fallbackTimeout);
+            }, { once: true });
+
+            window.location.href = link.dataset.appHref;
+        });
+    }
+}
+
+function setupThumbnailPreviews() {
+    const images = document.querySelectorAll("img[data-preview-src]");
+
+    for (let i = 0; i < images.length; i++) {
+        const image = images[i];
+        const parent = image.closest(".thumbnail-parent") || image;
+        let staticSrc;
+
+        parent.addEventListener("mouseenter", () => {
+            if (image.dataset.previewSrc === undefined) return;
+
+            // read on every hover since the fallback may have swapped it in the meantime
+            staticSrc = image.src;
+            image.src = image.dataset.previewSrc;
+        });
+
+        parent.addEventListener("mouseleave", () => {
+            if (staticSrc === undefined) return;
+
+            image.src = staticSrc;
+            staticSrc = undefined;
+        });
+
+        // previews are optional, a broken one shouldn't leave the card without an image
+        image.addEventListener("error", () => {
+            if (staticSrc === undefined || image.src === staticSrc) return;
+
+            image.src = staticSrc;
+            delete image.dataset.previewSrc;
+        });
+    }
+}
+
+function setupImageFallbacks() {
+    const images = document.querySelectorAll("img[data-fallback-src], img[data-default-src]");
+
+    if (images.length == 0) {
+        return;
+    }
+
+    const timers = new Map();
+
+    const swapToFallback = (image) => {
+        clearTimeout(timers.get(image));
+        timers.delete(image);
+
+        // the resized variants come from the same source that failed
+        image.removeAttribute("srcset");
+
+        if (image.dataset.fallbackSrc !== undefined) {
+            image.src = image.dataset.fallbackSrc;
+            delete image.dataset.fallbackSrc;
+
+            // the platform's default image is the last resort if the fallback fails too
+            if (image.dataset.defaultSrc !== undefined) {
+                image.addEventListener("error", () => swapToFallback(image), { once: true });
+            }
+
+            return;
+        }
+
+        if (image.dataset.defaultSrc !== undefined) {
+            image.src = image.dataset.defaultSrc;
+            delete image.dataset.defaultSrc;
+        }
+    };
+
+    // lazy images don't start loading until they're near the viewport,
+    // so the timeout only starts counting once the image is visible
+    const observer = new IntersectionObserver((entries) => {
+        for (let i = 0; i < entries.length; i++) {
+            const entry = entries[i];
+
+            if (!entry.isIntersecting) {
+                continue;
+            }
+
+            const image = entry.target;
+            observer.unobserve(image);
+
+            timers.set(image, setTimeout(() => {
+                if (!image.complete || image.naturalWidth == 0) {
+                    swapToFallback(image);
+                }
+            }, parseInt(image.dataset.fallbackTimeout)));
+        }
+    });
+
+    for (let i = 0; i < images.length; i++) {
+        const image = images[i];
+
+        if (image.complete) {
+            if (image.naturalWidth == 0) swapToFallback(image);
+            continue;
+        }
+
+        image.addEventListener("error", () => swapToFallback(image), { once: true });
+        image.addEventListener("load", () => {
+            clearTimeout(timers.get(image));
+            observer.unobserve(image);
+        }, { once: true });
+
+        if (parseInt(image.dataset.fallbackTimeout) > 0) {
+            observer.observe(image);
+        }
+    }
+}
+
+const prefersReducedMotion = window.matchMedia("(prefers-reduced-motion: reduce)");
+
+function animateContainerHeightChange(container, change) {
+    const duration = parseInt(container.dataset.collapseAnimationDuration);
+
+    if (isNaN(duration) || duration <= 0 || prefersReducedMotion.matches) {
+        change();
+        return;
+    }
+
+    const heightBefore = container.offsetHeight;
+    change();
+    const heightAfter = container.offsetHeight;
+
+    container.animate([
+        { height: `${heightBefore}px`, overflow: "hidden" },
+        { height: `${heightAfter}px`, overflow: "hidden" },
+    ], { duration: duration, easing: "ease-in-out" });
+}
+
+// Where the page was scrolled to when containers with a collapse key got
+// expanded, kept outside of the containers so that it survives them being
+// replaced when the content gets rendered again
+const collapseScrollPositions = new Map();
+
 function attachExpandToggleButton(collapsibleContainer) {
+    const collapseKey = collapsibleContainer.dataset.collapseKey;
     const showMoreText = "Show more";
     const showLessText = "Show less";
 
@@ -352,17 +583,37 @@ function attachExpandToggleButton(collapsibleContainer) {
         expanded = !expanded;
 
         if (expanded) {
-            collapsibleContainer.classList.add("container-expanded");
-            button.classList.add("container-expanded");
-            textNode.nodeValue = showLessText;
+            if (collapseKey !== undefined) {
+                collapseScrollPositions.set(collapseKey, window.scrollY);
+            }
+
+            animateContainerHeightChange(collapsibleContainer, () => {
+                collapsibleContainer.classList.add("container-expanded");
+                button.classList.add("container-expanded");
+                textNode.nodeValue = showLessText;
+            });
             return;
         }
 
         const topBefore = button.getClientRects()[0].top;
 
-        collapsibleContainer.classList.remove("container-expanded");
-        button.classList.remove("container-expanded");
-        textNode.nodeValue = showMoreText;
+        animateContainerHeightChange(collapsibleContainer, () => {
+            collapsibleContainer.classList.remove("container-expanded");
+            button.classList.remove("container-expanded");
+            textNode.nodeValue = showMoreText;
+        });
+
+        // return to where the page was before expanding rather than wherever
+        // the reflow happens to leave it
+        if (collapseKey !== undefined && collapseScrollPositions.has(collapseKey)) {
+            window.scrollTo({
+                top: collapseScrollPositions.get(collapseKey),
+                behavior: "instant"
+            });
+
+            collapseScrollPositions.delete(collapseKey);
+            return;
+        }
 
         const topAfter = button.getClientRects()[0].top;
 
@@ -415,6 +666,19 @@ function setupCollapsibleLists() {
     }
 }
 
+// Same widths as the media queries in main.css
+function getPageBreakpoint() {
+    if (window.matchMedia("(max-width: 550px)").matches) {
+        return "mobile";
+    }
+
+    if (window.matchMedia("(max-width: 1190px)").matches) {
+        return "tablet";
+    }
+
+    return "desktop";
+}
+
 function setupCollapsibleGrids() {
     const collapsibleGridElements = document.querySelectorAll(".cards-grid.collapsible-container");
 
@@ -430,8 +694,14 @@ function setupCollapsibleGrids() {
         }
 
         const collapseAfterRows = parseInt(gridElement.dataset.collapseAfterRows);
+        const breakpointLimits = {
+            mobile: parseInt(gridElement.dataset.limitMobile),
+            tablet: parseInt(gridElement.dataset.limitTablet),
+            desktop: parseInt(gridElement.dataset.limitDesktop),
+        };
+        const hasBreakpointLimits = Object.values(breakpointLimits).some((limit) => !isNaN(limit));
 
-        if (collapseAfterRows == -1) {
+        if (collapseAfterRows == -1 && !hasBreakpointLimits) {
             continue;
         }
 
@@ -442,10 +712,16 @@ function setupCollapsibleGrids() {
         const button = attachExpandToggleButton(gridElement);
 
         let cardsPerRow;
+        let breakpoint;
 
         const resolveCollapsibleItems = () => requestAnimationFrame(() => {
-            const hideItemsAfterIndex = cardsPerRow * collapseAfterRows;
+            const breakpointLimit = breakpointLimits[breakpoint];
+            const hideItemsAfterIndex = !isNaN(breakpointLimit)
+                ? breakpointLimit
+                : collapseAfterRows == -1 ? Infinity : cardsPerRow * collapseAfterRows;
 
+            // how many cards fit depends on how many there are per row, so
+            // only here can it be known whether there's anything to collapse
             if (hideItemsAfterIndex >= gridElement.children.length) {
                 button.style.display = "none";
             } else {
@@ -477,12 +753,14 @@ function setupCollapsibleGrids() {
             }
 
             const newCardsPerRow = getCardsPerRow();
+            const newBreakpoint = getPageBreakpoint();
 
-            if (cardsPerRow == newCardsPerRow) {
+            if (cardsPerRow == newCardsPerRow && breakpoint == newBreakpoint) {
                 return;
             }
 
             cardsPerRow = newCardsPerRow;
+            breakpoint = newBreakpoint;
             resolveCollapsibleItems();
         });
 
@@ -656,9 +934,11 @@ function setupTruncatedElementTitles() {
 async function setupPage() {
     const pageElement = document.getElementById("page");
     const pageContentElement = document.getElementById("page-content");
-    const pageContent = await fetchPageContent(pageData);
 
-    pageContentElement.innerHTML = pageContent;
+    // embedded widgets come already rendered, they only need setting up
+    if (!pageData.embedded) {
+        pageContentElement.innerHTML = await fetchPageContent(pageData);
+    }
 
     try {
         setupPopovers();
@@ -671,6 +951,11 @@ async function setupPage() {
         setupGroups();
         setupMasonries();
         setupDynamicRelativeTime();
+        setupRefreshCountdowns();
+        setupClickTracking();
+        setupAppDeepLinks();
+        setupImageFallbacks();
+        setupThumbnailPreviews();
         setupLazyImages();
     } finally {
         pageElement.classList.add("content-ready");
@@ -0,0 +1,155 @@
+package glance
+
+import (
+	"fmt"
+	"html/template"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+const (
+	// thumbnails are large enough that a few thousand pixels are plenty to
+	// get a good idea of the colors in them
+	thumbnailColorSamples = 4096
+	maxThumbnailColorSize = 4 * 1024 * 1024
+	// past this many entries the cache gets emptied, thumbnails of videos that
+	// are still shown get their color recomputed over the next updates
+	maxCachedThumbnailColors = 5000
+)
+
+// Limits how many thumbnails get fetched at once in the background
+var thumbnailColorSlots = make(chan struct{}, 4)
+
+// The color of a thumbnail never changes, so it gets computed once and shared
+// between all widgets. Failed attempts get cached as an empty color so that
+// broken thumbnails don't get fetched on every update
+var thumbnailColorCache = struct {
+	mu      sync.Mutex
+	byUrl   map[string]template.CSS
+	pending map[string]struct{}
+}{
+	byUrl:   make(map[string]template.CSS),
+	pending: make(map[string]struct{}),
+}
+
+// Returns the cached color of the thumbnail, starting to compute it in the
+// background when it isn't known yet so that it's there by the next update
+func cachedThumbnailColor(url string) (template.CSS, bool) {
+	thumbnailColorCache.mu.Lock()
+	defer thumbnailColorCache.mu.Unlock()
+
+	if color, exists := thumbnailColorCache.byUrl[url]; exists {
+		return color, true
+	}
+
+	if _, exists := thumbnailColorCache.pending[url]; !exists {
+		thumbnailColorCache.pending[url] = struct{}{}
+		go computeThumbnailColor(url)
+	}
+
+	return "", false
+}
+
+func computeThumbnailColor(url string) {
+	thumbnailColorSlots <- struct{}{}
+	color, err := fetchThumbnailColor(url)
+	<-thumbnailColorSlots
+
+	if err != nil {
+		slog.Debug("Could not compute thumbnail color", "url", url, "error", err)
+	}
+
+	thumbnailColorCache.mu.Lock()
+	defer thumbnailColorCache.mu.Unlock()
+
+	if len(thumbnailColorCache.byUrl) >= maxCachedThumbnailColors {
+		clear(thumbnailColorCache.byUrl)
+	}
+
+	thumbnailColorCache.byUrl[url] = color
+	delete(thumbnailColorCache.pending, url)
+}
+
+func fetchThumbnailColor(url string) (template.CSS, error) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	setBrowserUserAgentHeader(request)
+
+	response, err := defaultHTTPClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(response.Body, maxThumbnailColorSize))
+	if err != nil {
+		return "", err
+	}
+
+	r, g, b := dominantImageColor(img)
+
+	return template.CSS(fmt.Sprintf("rgb(%d, %d, %d)", r, g, b)), nil
+}
+
+// Groups a sample of the pixels into buckets of similar colors and returns
+// the average color of the largest bucket, which unlike the average of the
+// whole image doesn't end up a muddy gray for colorful images
+func dominantImageColor(img image.Image) (uint8, uint8, uint8) {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+
+	bounds := img.Bounds()
+	step := max(1, bounds.Dx()*bounds.Dy()/thumbnailColorSamples)
+	buckets := make(map[int]*bucket)
+
+	var largest *bucket
+
+	for i := 0; i < bounds.Dx()*bounds.Dy(); i += step {
+		x := bounds.Min.X + i%bounds.Dx()
+		y := bounds.Min.Y + i/bounds.Dx()
+
+		pr, pg, pb, pa := img.At(x, y).RGBA()
+		// mostly transparent pixels aren't what the image looks like
+		if pa < 0x8000 {
+			continue
+		}
+
+		r, g, b := int(pr>>8), int(pg>>8), int(pb>>8)
+		key := r>>5<<6 | g>>5<<3 | b>>5
+
+		current, exists := buckets[key]
+		if !exists {
+			current = &bucket{}
+			buckets[key] = current
+		}
+
+		current.count++
+		current.r += r
+		current.g += g
+		current.b += b
+
+		if largest == nil || current.count > largest.count {
+			largest = current
+		}
+	}
+
+	if largest == nil {
+		return 0, 0, 0
+	}
+
+	return uint8(largest.r / largest.count), uint8(largest.g / largest.count), uint8(largest.b / largest.count)
+}
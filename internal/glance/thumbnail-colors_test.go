@@ -0,0 +1,88 @@
+package glance
+
+import (
+	"bytes"
+	"html/template"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A thumbnail that's mostly red with a blue stripe at the bottom
+func testThumbnailImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 36))
+
+	for y := range 36 {
+		for x := range 64 {
+			if y < 24 {
+				img.Set(x, y, color.RGBA{200, 30, 30, 255})
+			} else {
+				img.Set(x, y, color.RGBA{20, 40, 220, 255})
+			}
+		}
+	}
+
+	return img
+}
+
+func TestDominantImageColor(t *testing.T) {
+	r, g, b := dominantImageColor(testThumbnailImage())
+
+	if r != 200 || g != 30 || b != 30 {
+		t.Errorf("expected the dominant color to be rgb(200, 30, 30), got rgb(%d, %d, %d)", r, g, b)
+	}
+}
+
+func TestCachedThumbnailColor(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testThumbnailImage()); err != nil {
+		t.Fatalf("encoding image: %v", err)
+	}
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(encoded.Bytes())
+	}))
+	defer server.Close()
+
+	url := server.URL + "/thumbnail.png"
+
+	// the color is only known once it has been computed in the background
+	if got, cached := cachedThumbnailColor(url); cached || got != "" {
+		t.Fatalf("expected the color to not be cached yet, got %q", got)
+	}
+
+	var got template.CSS
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var cached bool
+		if got, cached = cachedThumbnailColor(url); cached {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("expected the color to get computed")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if want := template.CSS("rgb(200, 30, 30)"); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	for range 3 {
+		cachedThumbnailColor(url)
+	}
+
+	if fetched := requests.Load(); fetched != 1 {
+		t.Errorf("expected the thumbnail to only be fetched once, got %d requests", fetched)
+	}
+}
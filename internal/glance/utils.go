@@ -11,6 +11,7 @@ import (
 	"slices"
 	"strings"
 	"time"
+	"unicode"
 )
 
 var sequentialWhitespacePattern = regexp.MustCompile(`\s+`)
@@ -110,6 +111,33 @@ func limitStringLength(s string, max int) (string, bool) {
 	return s, false
 }
 
+// Truncates the string to at most max runes, cutting at the last word
+// boundary before the limit and appending an ellipsis. Text without spaces
+// near the limit, such as CJK text, gets cut at the rune instead
+func truncateAtWordBoundary(s string, max int) (string, bool) {
+	asRunes := []rune(s)
+
+	if len(asRunes) <= max {
+		return s, false
+	}
+
+	cut := asRunes[:max]
+
+	// don't give up more than half of the text just to land on a space
+	for i := len(cut) - 1; i >= max/2; i-- {
+		if unicode.IsSpace(cut[i]) {
+			cut = cut[:i]
+			break
+		}
+	}
+
+	truncated := strings.TrimRightFunc(string(cut), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+
+	return truncated + "…", true
+}
+
 func parseRFC3339Time(t string) time.Time {
 	parsed, err := time.Parse(time.RFC3339, t)
 	if err != nil {
@@ -0,0 +1,30 @@
+package glance
+
+import "testing"
+
+func TestTruncateAtWordBoundary(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		max           int
+		want          string
+		wantTruncated bool
+	}{
+		{"short enough", "short", 10, "short", false},
+		{"exactly the limit", "exactly", 7, "exactly", false},
+		{"cut at a space", "hello world again", 13, "hello world…", true},
+		{"trailing punctuation", "hello, world", 8, "hello…", true},
+		{"no space past half", "a verylongwordwithoutspaces", 10, "a verylong…", true},
+		{"cjk", "这是一个没有空格的很长的标题", 5, "这是一个没…", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, truncated := truncateAtWordBoundary(test.input, test.max)
+
+			if got != test.want || truncated != test.wantTruncated {
+				t.Errorf("expected %q (%v), got %q (%v)", test.want, test.wantTruncated, got, truncated)
+			}
+		})
+	}
+}
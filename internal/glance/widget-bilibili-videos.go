@@ -1,39 +1,237 @@
 package glance
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"html/template"
-	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 var (
-	bilibiliVideosWidgetTemplate             = mustParseTemplate("videos.html", "widget-base.html", "video-card-contents.html")
-	bilibiliVideosWidgetGridTemplate         = mustParseTemplate("videos-grid.html", "widget-base.html", "video-card-contents.html")
-	bilibiliVideosWidgetVerticalListTemplate = mustParseTemplate("videos-vertical-list.html", "widget-base.html")
+	bilibiliVideosWidgetTemplate               = mustParseTemplate("bilibili-videos.html", "widget-base.html", "bilibili-video-card-contents.html")
+	bilibiliVideosWidgetGridTemplate           = mustParseTemplate("bilibili-videos-grid.html", "widget-base.html", "bilibili-video-card-contents.html")
+	bilibiliVideosWidgetVerticalListTemplate   = mustParseTemplate("bilibili-videos-vertical-list.html", "widget-base.html", "bilibili-video-card-contents.html")
+	bilibiliVideosWidgetAuthorCarouselTemplate = mustParseTemplate("bilibili-videos-author-carousel.html", "widget-base.html", "bilibili-video-card-contents.html")
 )
 
 type bilibiliVideosWidget struct {
 	widgetBase        `yaml:",inline"`
-	Videos            bilibiliVideoList `yaml:"-"`
-	VideoUrlTemplate  string            `yaml:"video-url-template"`
-	Style             string            `yaml:"style"`
-	CollapseAfter     int               `yaml:"collapse-after"`
-	CollapseAfterRows int               `yaml:"collapse-after-rows"`
-	RSSHubUrls        []string          `yaml:"rsshuburls"`
-	Limit             int               `yaml:"limit"`
-	IncludeShorts     bool              `yaml:"include-shorts"`
-	ImageProxy        string            `yaml:"image-proxy"`
+	Videos            bilibiliVideoList    `yaml:"-"`
+	FeaturedVideo     *bilibiliVideo       `yaml:"-"`
+	ArchivedVideos    bilibiliVideoList    `yaml:"-"`
+	Groups            []bilibiliVideoGroup `yaml:"-"`
+	PeriodGroups      []bilibiliVideoGroup `yaml:"-"`
+	VideoUrlTemplate  string               `yaml:"video-url-template"`
+	Style             string               `yaml:"style"`
+	Group             string               `yaml:"group"`
+	CollapseAfter     int                  `yaml:"collapse-after"`
+	CollapseAfterRows int                  `yaml:"collapse-after-rows"`
+	// how many cards the grid-cards style shows before collapsing the rest at each
+	// of the page's breakpoints, overriding collapse-after-rows where set
+	BreakpointLimits  bilibiliBreakpointLimits `yaml:"breakpoint-limits"`
+	RSSHubUrls        []bilibiliFeedField      `yaml:"rsshuburls"`
+	Limit             int                      `yaml:"limit"`
+	IncludeShorts     bool                     `yaml:"include-shorts"`
+	ImageProxy        string                   `yaml:"image-proxy"`
+	RewriteHost       string                   `yaml:"rewrite-host"`
+	AuthorUrlTemplate string                   `yaml:"author-url-template"`
+	// shows the avatar of each video's uploader, the template's {UID} (or {uid})
+	// gets replaced with the ID of the uploader, unless the feed provides the
+	// avatar itself
+	AvatarUrlTemplate string        `yaml:"avatar-url-template"`
+	Tolerant          bool          `yaml:"tolerant"`
+	RequireThumbnail  bool          `yaml:"require-thumbnail"`
+	ArchiveAfter      durationField `yaml:"archive-after"`
+	Gap               float64       `yaml:"gap"`
+	CardPadding       float64       `yaml:"card-padding"`
+	TitleLines        int           `yaml:"title-lines"`
+	// how long the browser waits for a proxied thumbnail before falling back
+	// to the direct source URL, disabled when not set
+	ImageProxyTimeout durationField `yaml:"image-proxy-timeout"`
+	// images shown when a thumbnail can't be loaded at all, keyed by the domain
+	// of the platform the video is on, with * applying to any other platform
+	DefaultThumbnails map[string]string `yaml:"default-thumbnails"`
+	ShowSourceFavicon bool              `yaml:"show-source-favicon"`
+	// either platform, which shows the icon of known platforms and a generic
+	// icon for everything else, or favicon, which uses the site's favicon
+	// instead of the generic icon
+	PlatformIcon string `yaml:"platform-icon"`
+	// what to do when some of the feeds fail: show, retry-once or keep-previous
+	OnPartial string `yaml:"on-partial"`
+	GroupBy   string `yaml:"group-by"`
+	Timezone  string `yaml:"timezone"`
+	// sections shows the videos of each feed separately, each with its own
+	// collapsible list
+	Layout string `yaml:"layout"`
+	// either widget or page, which leaves out videos already shown by
+	// widgets that come before this one on the same page
+	DedupScope string `yaml:"dedup-scope"`
+	// either url, title or normalized-title, which ignores case, whitespace,
+	// punctuation and emoji so that slightly reworded cross-posts collapse
+	Dedup string `yaml:"dedup"`
+	// either first or best-thumbnail, which keeps whichever duplicate has the
+	// thumbnail with the highest resolution, when the resolutions are known
+	DedupKeep string `yaml:"dedup-keep"`
+	// either merge, which fills in whatever the kept duplicate is missing from
+	// the ones that got left out, or discard
+	DedupMetadata string `yaml:"dedup-metadata"`
+	// square crops the thumbnails of the vertical-list style for more compact rows
+	ListThumbnail string `yaml:"list-thumbnail"`
+	// subdomains of the image proxy that thumbnails get spread across so that
+	// browsers can load more of them in parallel, e.g. [img1, img2]
+	ImageProxyShards []string `yaml:"image-proxy-shards"`
+	// widths the image proxy resizes card thumbnails to, letting browsers
+	// pick the smallest one that's sharp enough, e.g. [320, 640, 960]
+	ThumbnailWidths []int `yaml:"thumbnail-widths"`
+	// the query parameter of the image proxy that sets the width, w by default
+	ThumbnailWidthParam string `yaml:"thumbnail-width-param"`
+	// how wide the thumbnails are shown, in the format of the sizes attribute
+	ThumbnailSizes string   `yaml:"thumbnail-sizes"`
+	BlockAuthors   []string `yaml:"block-authors"`
+	// fields from JSON Feed extension objects to show as labels on each video
+	ExtensionFields []string `yaml:"extension-fields"`
+	RetryOnEmpty    bool     `yaml:"retry-on-empty"`
+	TrackClicks     bool     `yaml:"track-clicks"`
+	// the widget stays hidden while it has fewer videos than this
+	MinItems int `yaml:"min-items"`
+	// maps author names as they appear in the feed to the names shown instead
+	AuthorAliases map[string]string `yaml:"author-aliases"`
+	// either auto, which picks a color for each author based on their name,
+	// or a map of authors to colors, with everyone else still picked automatically
+	AuthorColors authorColorsField `yaml:"author-colors"`
+	// tints the background of each card with the dominant color of its
+	// thumbnail, which gets computed in the background and shows up from
+	// the update after the thumbnail was first seen
+	ThumbnailColors bool `yaml:"thumbnail-colors"`
+	// how long resolved feed hosts are cached for, disabled when not set
+	DNSCache durationField `yaml:"dns-cache"`
+	// how many redirects are followed when fetching a feed before giving up
+	MaxRedirects int `yaml:"max-redirects"`
+	// how long a single feed can take, including reading its body
+	RequestTimeout durationField `yaml:"request-timeout"`
+	// how long fetching all of the feeds can take, including any retries
+	Deadline durationField `yaml:"deadline"`
+	// how many videos each author can have per day, in the widget's timezone
+	PerAuthorDailyCap int `yaml:"per-author-daily-cap"`
+	// either newest or weighted, which blends how recent videos are with the
+	// weight of their feed, halving the score of a video every half-life
+	Sort     string        `yaml:"sort"`
+	HalfLife durationField `yaml:"half-life"`
+	// either time or feed, which keeps the order videos have in their feed
+	// and lists the feeds one after the other in the order they're configured
+	Order string `yaml:"order"`
+	// feeds going over either of these fail instead of being read in full
+	MaxFeedItems int   `yaml:"max-feed-items"`
+	MaxFeedBytes int64 `yaml:"max-feed-bytes"`
+	// the longest the widget waits before retrying when every feed failed
+	RetryAfterTotalFailure durationField `yaml:"retry-after-total-failure"`
+	// an extra line on each card, e.g. "{author} · {date} · {source}"
+	CardFooterFormat string `yaml:"card-footer-format"`
+	// on touch devices, tries opening videos in the bilibili app before
+	// falling back to the website
+	AppDeepLinks bool `yaml:"app-deep-links"`
+	// swaps the thumbnail for the animated preview of the video while hovering
+	// over it, for feeds that provide one through the item's preview field
+	HoverPreview bool `yaml:"hover-preview"`
+	// converts feeds that aren't in UTF-8 to it based on their BOM or the
+	// charset of their Content-Type, for routes that proxy e.g. GBK sources
+	TranscodeCharsets bool `yaml:"transcode-charsets"`
+	// sends the validators of previous responses with each request, when no
+	// feed has changed the widget keeps serving what it rendered last time
+	ConditionalRequests bool `yaml:"conditional-requests"`
+	// smoothly animates the height of the list when expanding and collapsing
+	// it, the duration is in milliseconds
+	CollapseAnimation         *bool `yaml:"collapse-animation"`
+	CollapseAnimationDuration int   `yaml:"collapse-animation-duration"`
+	// drops items missing a title, URL or date and logs why, which makes it
+	// obvious when an RSSHub route changes the format of its feed
+	ValidateItems bool `yaml:"validate-items"`
+	// for feeds that paginate, the most pages fetched from each of them while
+	// there aren't enough videos to reach the limit
+	Pages int `yaml:"pages"`
+	// shows the newest video of this author as a large card above the rest
+	FeatureAuthor string `yaml:"feature-author"`
+	// drops videos shorter than this, based on the duration of the item's
+	// attachments, videos without a known duration are only dropped if
+	// drop-unknown-duration is set
+	MinDuration         durationField `yaml:"min-duration"`
+	DropUnknownDuration bool          `yaml:"drop-unknown-duration"`
+	// fetches every feed with a single POST request to an aggregator, which
+	// gets sent {"feeds": [urls]} and responds with {"feeds": [...]}, where
+	// each entry is a JSON feed with the url it came from and optionally an
+	// error for feeds it failed to fetch
+	Aggregator string `yaml:"aggregator"`
+	// moves live streams and upcoming premieres above everything else
+	LiveFirst bool `yaml:"live-first"`
+	// either mark or drop, for videos that share their thumbnail with a video
+	// that comes before them, which spam feeds tend to do
+	FlagDuplicateThumbnails string `yaml:"flag-duplicate-thumbnails"`
+	// only shows the newest video of a feed the first time it gets fetched and
+	// from then on only videos posted after it, so that newly added channels
+	// don't flood the widget with their backlog. The baselines are kept in
+	// memory and get set again after restarting
+	SkipBacklog bool `yaml:"skip-backlog"`
+	// what screen readers announce for each video, {title}, {author}, {time}
+	// and {date} get replaced with the video's details
+	AriaLabelFormat string `yaml:"aria-label-format"`
+	// a URL that gets sent a POST request after updates that brought in
+	// videos which weren't there during the previous update
+	OnUpdateWebhook string `yaml:"on-update-webhook"`
+	// rather than always fetching up to 30 feeds at once, starts with a few
+	// and adds more while the feeds respond quickly and without errors
+	AdaptiveConcurrency bool `yaml:"adaptive-concurrency"`
+
+	location            *time.Location
+	shardedImageProxies []string
+	feedCache           map[string]bilibiliFeedCacheEntry
+	hasCompleteContent  bool
+	client              *http.Client
+	conditional         *conditionalTransport
+	feedsUnchanged      bool
+	renderCache         template.HTML
+	pageSeen            map[string]struct{}
+	failedFeeds         int
+	lastSeenSequence    map[string]int64
+	backlogBaselines    map[string]time.Time
+	previousVideoUrls   map[string]struct{}
+	sequenceGaps        []string
+	concurrencyLimit    *adaptiveConcurrencyLimit
 }
 
 func (widget *bilibiliVideosWidget) initialize() error {
 	widget.withTitle("Videos").withCacheDuration(time.Hour)
 
+	// disabled feeds are left out entirely rather than skipped when fetching
+	// so that everything that goes by the index of a feed stays consistent
+	widget.RSSHubUrls = slices.DeleteFunc(widget.RSSHubUrls, func(feed bilibiliFeedField) bool {
+		return feed.Disabled
+	})
+
+	// feeds with a shorter cache than the widget's can only be refetched as
+	// often as the widget updates, so it has to update at least that often
+	for i := range widget.RSSHubUrls {
+		feedCache := time.Duration(widget.RSSHubUrls[i].Cache)
+		if feedCache > 0 && feedCache < widget.cacheDuration {
+			widget.cacheDuration = feedCache
+		}
+	}
+
+	widget.feedCache = make(map[string]bilibiliFeedCacheEntry)
+
 	if widget.Limit <= 0 {
 		widget.Limit = 25
 	}
@@ -46,143 +244,1394 @@ func (widget *bilibiliVideosWidget) initialize() error {
 		widget.CollapseAfter = 7
 	}
 
+	if widget.BreakpointLimits.Mobile < 0 || widget.BreakpointLimits.Tablet < 0 || widget.BreakpointLimits.Desktop < 0 {
+		return errors.New("breakpoint-limits can not be negative")
+	}
+
+	if widget.Gap < 0 {
+		widget.Gap = 0
+	}
+
+	if widget.CardPadding < 0 {
+		widget.CardPadding = 0
+	}
+
+	// enabled unless explicitly turned off, a duration of 0 disables it when rendering
+	if widget.CollapseAnimation != nil && !*widget.CollapseAnimation {
+		widget.CollapseAnimationDuration = 0
+	} else if widget.CollapseAnimationDuration <= 0 {
+		widget.CollapseAnimationDuration = 200
+	}
+
+	if widget.TitleLines <= 0 {
+		widget.TitleLines = 2
+	}
+
+	if widget.AdaptiveConcurrency {
+		widget.concurrencyLimit = newAdaptiveConcurrencyLimit()
+	}
+
+	if widget.Group != "" && widget.Group != "author-carousel" {
+		return errors.New("group must be author-carousel")
+	}
+
+	if widget.GroupBy != "" {
+		if widget.GroupBy != "day" && widget.GroupBy != "week" {
+			return errors.New("group-by must be either day or week")
+		}
+
+		if widget.Style != "vertical-list" {
+			return errors.New("group-by is only supported with the vertical-list style")
+		}
+	}
+
+	if widget.Layout != "" {
+		if widget.Layout != "sections" {
+			return errors.New("layout must be sections")
+		}
+
+		if widget.Style != "vertical-list" {
+			return errors.New("layout sections is only supported with the vertical-list style")
+		}
+
+		if widget.GroupBy != "" || widget.Group != "" {
+			return errors.New("layout sections can't be used together with group or group-by")
+		}
+	}
+
+	if widget.ListThumbnail != "" {
+		if widget.ListThumbnail != "square" {
+			return errors.New("list-thumbnail must be square")
+		}
+
+		if widget.Style != "vertical-list" {
+			return errors.New("list-thumbnail is only supported with the vertical-list style")
+		}
+	}
+
+	switch widget.Dedup {
+	case "", "url", "title", "normalized-title":
+	default:
+		return errors.New("dedup must be either url, title or normalized-title")
+	}
+
+	switch widget.DedupKeep {
+	case "", "first", "best-thumbnail":
+	default:
+		return errors.New("dedup-keep must be either first or best-thumbnail")
+	}
+
+	switch widget.DedupMetadata {
+	case "", "merge", "discard":
+	default:
+		return errors.New("dedup-metadata must be either merge or discard")
+	}
+
+	switch widget.DedupScope {
+	case "", "widget":
+	case "page":
+		if widget.Group != "" || widget.GroupBy != "" || widget.Layout != "" {
+			return errors.New("dedup-scope page can't be used together with group, group-by or layout")
+		}
+	default:
+		return errors.New("dedup-scope must be either widget or page")
+	}
+
+	widget.location = time.Local
+	if widget.Timezone != "" {
+		location, err := time.LoadLocation(widget.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone: %v", err)
+		}
+
+		widget.location = location
+	}
+
+	switch widget.Sort {
+	case "":
+		widget.Sort = "newest"
+	case "newest", "weighted":
+	default:
+		return errors.New("sort must be either newest or weighted")
+	}
+
+	switch widget.Order {
+	case "":
+		widget.Order = "time"
+	case "time":
+	case "feed":
+		if widget.Sort == "weighted" {
+			return errors.New("order feed can't be used with the weighted sort")
+		}
+
+		if widget.GroupBy != "" {
+			return errors.New("order feed can't be used with group-by")
+		}
+	default:
+		return errors.New("order must be either time or feed")
+	}
+
+	if widget.HalfLife <= 0 {
+		widget.HalfLife = durationField(24 * time.Hour)
+	}
+
+	switch widget.PlatformIcon {
+	case "":
+	case "platform", "favicon":
+		if widget.ShowSourceFavicon {
+			return errors.New("platform-icon can't be used together with show-source-favicon")
+		}
+	default:
+		return errors.New("platform-icon must be either platform or favicon")
+	}
+
+	if widget.FeatureAuthor != "" {
+		if widget.Style == "vertical-list" || widget.Group != "" || widget.GroupBy != "" || widget.Layout != "" {
+			return errors.New("feature-author can't be used with the vertical-list style, group, group-by or layout")
+		}
+
+		if widget.DedupScope == "page" {
+			return errors.New("feature-author can't be used with dedup-scope page")
+		}
+	}
+
+	switch widget.FlagDuplicateThumbnails {
+	case "", "mark", "drop":
+	default:
+		return errors.New("flag-duplicate-thumbnails must be either mark or drop")
+	}
+
+	if widget.AriaLabelFormat == "" {
+		widget.AriaLabelFormat = "{title} by {author}, {time}"
+	}
+
+	if widget.Pages < 0 {
+		return errors.New("pages must be a positive number")
+	}
+
+	switch widget.OnPartial {
+	case "":
+		widget.OnPartial = "show"
+	case "show", "retry-once", "keep-previous":
+	default:
+		return errors.New("on-partial must be one of show, retry-once or keep-previous")
+	}
+
+	if widget.RewriteHost != "" {
+		if _, err := rewriteURLHost("http://localhost/", widget.RewriteHost); err != nil {
+			return fmt.Errorf("invalid rewrite-host: %v", err)
+		}
+	}
+
+	if widget.AuthorUrlTemplate == "" {
+		widget.AuthorUrlTemplate = "https://space.bilibili.com/{UID}"
+	}
+
 	if widget.ImageProxy == "" {
 		widget.ImageProxy = "//wsrv.nl/?url="
 	}
 
+	if len(widget.ThumbnailWidths) > 0 {
+		for _, width := range widget.ThumbnailWidths {
+			if width <= 0 {
+				return errors.New("thumbnail-widths must be greater than 0")
+			}
+		}
+
+		if widget.ThumbnailWidthParam == "" {
+			widget.ThumbnailWidthParam = "w"
+		}
+
+		if widget.ThumbnailSizes == "" {
+			widget.ThumbnailSizes = "(max-width: 550px) 100vw, 320px"
+		}
+	}
+
+	if len(widget.ImageProxyShards) > 0 {
+		proxyUrl, err := url.Parse(widget.ImageProxy)
+		if err != nil || proxyUrl.Host == "" {
+			return errors.New("image-proxy-shards requires image-proxy to be an absolute URL")
+		}
+
+		widget.shardedImageProxies = make([]string, len(widget.ImageProxyShards))
+		for i, shard := range widget.ImageProxyShards {
+			shardUrl := *proxyUrl
+			shardUrl.Host = strings.Trim(shard, ".") + "." + proxyUrl.Host
+			widget.shardedImageProxies[i] = shardUrl.String()
+		}
+	}
+
 	return nil
 }
 
 func (widget *bilibiliVideosWidget) update(ctx context.Context) {
-	videos, err := fetchBilibiliChannelUploads(widget.RSSHubUrls, widget.VideoUrlTemplate, widget.IncludeShorts, widget.ImageProxy)
+	startedAt := time.Now()
+	feedUrls := widget.feedUrls()
+	widget.ensureClient()
+
+	if widget.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(widget.Deadline))
+		defer cancel()
+	}
+
+	options := widget.fetchOptions(ctx, feedUrls)
+
+	videos, err := widget.fetchVideos(options)
+
+	if errors.Is(err, errPartialContent) && widget.OnPartial == "retry-once" {
+		// only use the retry if it didn't end up doing worse than the first attempt
+		if retried, retryErr := widget.fetchVideos(options); !errors.Is(retryErr, errNoContent) {
+			videos, err = retried, retryErr
+		}
+	}
+
+	widget.recordUpdateMetrics(startedAt, len(widget.RSSHubUrls))
+	widget.recordFeedFailures(widget.failedFeeds, len(feedUrls))
+	defer widget.applyCircuitBreaker()
+
+	// nothing has changed since the last update, so what got rendered then is still accurate
+	if err == nil && widget.feedsUnchanged && widget.hasCompleteContent && widget.renderCache != "" {
+		widget.canContinueUpdateAfterHandlingErr(nil)
+		return
+	}
+
+	widget.renderCache = ""
 
 	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		if widget.RetryAfterTotalFailure > 0 && errors.Is(err, errNoContent) {
+			widget.scheduleUpdateWithin(time.Duration(widget.RetryAfterTotalFailure))
+		}
+
+		return
+	}
+
+	if len(widget.sequenceGaps) > 0 {
+		widget.withNotice(errors.Join(widget.Notice, errors.New(strings.Join(widget.sequenceGaps, "; "))))
+	}
+
+	if err != nil && widget.OnPartial == "keep-previous" && widget.hasCompleteContent {
 		return
 	}
 
+	widget.hasCompleteContent = err == nil
+
+	if widget.Dedup != "" {
+		videos = videos.deduplicate(widget.Dedup, widget.DedupKeep == "best-thumbnail", widget.DedupMetadata != "discard")
+	}
+
+	if widget.FlagDuplicateThumbnails != "" {
+		videos = videos.flagDuplicateThumbnails(widget.FlagDuplicateThumbnails == "drop")
+	}
+
+	videos.withAriaLabelFormat(widget.AriaLabelFormat, widget.location)
+
+	if widget.Sort == "weighted" {
+		videos.sortByWeightedRecency(time.Duration(widget.HalfLife), time.Now())
+	}
+
+	if widget.PerAuthorDailyCap > 0 {
+		videos = videos.capPerAuthorPerDay(widget.PerAuthorDailyCap, widget.location)
+	}
+
+	var archived bilibiliVideoList
+	if widget.ArchiveAfter > 0 {
+		videos, archived = videos.partitionByAge(time.Duration(widget.ArchiveAfter))
+
+		if len(archived) > widget.Limit {
+			archived = archived[:widget.Limit]
+		}
+	}
+
+	if widget.LiveFirst {
+		videos.moveLiveToFront()
+	}
+
+	if widget.FeatureAuthor != "" {
+		videos.moveNewestByAuthorToFront(widget.FeatureAuthor)
+	}
+
 	if len(videos) > widget.Limit {
 		videos = videos[:widget.Limit]
 	}
 
+	if widget.AuthorColors.enabled {
+		videos.withAuthorColors(&widget.AuthorColors)
+	}
+
+	if widget.ThumbnailColors {
+		videos.withThumbnailColors()
+	}
+
+	if len(widget.shardedImageProxies) > 0 {
+		videos.withShardedImageProxy(widget.ImageProxy, widget.shardedImageProxies)
+		archived.withShardedImageProxy(widget.ImageProxy, widget.shardedImageProxies)
+	}
+
+	// done after sharding so that the resized variants come from the same shard
+	if len(widget.ThumbnailWidths) > 0 {
+		videos.withThumbnailSrcset(widget.ThumbnailWidths, widget.ThumbnailWidthParam, widget.ThumbnailSizes)
+	}
+
+	if widget.ImageProxyTimeout > 0 {
+		videos.withThumbnailFallback(time.Duration(widget.ImageProxyTimeout))
+		archived.withThumbnailFallback(time.Duration(widget.ImageProxyTimeout))
+	}
+
+	if len(widget.DefaultThumbnails) > 0 {
+		videos.withDefaultThumbnails(widget.DefaultThumbnails)
+	}
+
+	if widget.CardFooterFormat != "" {
+		videos.withFooter(widget.CardFooterFormat, widget.location)
+	}
+
+	if widget.AppDeepLinks {
+		videos.withAppUrls()
+		archived.withAppUrls()
+	}
+
+	if widget.ShowSourceFavicon {
+		videos.withSourceIcons(widget.Providers.assetResolver("icons/bilibili.svg"))
+	} else if widget.PlatformIcon != "" {
+		videos.withPlatformIcons(widget.PlatformIcon == "favicon", widget.Providers.assetResolver)
+	}
+
+	widget.FeaturedVideo = nil
+	if widget.FeatureAuthor != "" && len(videos) > 0 && strings.EqualFold(videos[0].Author, widget.FeatureAuthor) {
+		widget.FeaturedVideo = &videos[0]
+		videos = videos[1:]
+	}
+
 	widget.Videos = videos
-}
+	widget.ArchivedVideos = archived
 
-func (widget *bilibiliVideosWidget) Render() template.HTML {
-	var template *template.Template
+	if widget.Group == "author-carousel" {
+		widget.Groups = videos.groupByAuthor()
+	}
 
-	switch widget.Style {
-	case "grid-cards":
-		template = bilibiliVideosWidgetGridTemplate
-	case "vertical-list":
-		template = bilibiliVideosWidgetVerticalListTemplate
-	default:
-		template = bilibiliVideosWidgetTemplate
+	if widget.Layout == "sections" {
+		widget.Groups = videos.groupByFeed(len(widget.RSSHubUrls))
+	}
+
+	if widget.GroupBy != "" {
+		widget.PeriodGroups = videos.groupByPeriod(widget.GroupBy, time.Now().In(widget.location))
 	}
 
-	return widget.renderTemplate(widget, template)
+	if widget.OnUpdateWebhook != "" {
+		widget.notifyAboutNewVideos()
+	}
 }
 
-type bilibiliFeedResponseJson struct {
-	Version     string `json:"version"`
-	Title       string `json:"title"`
-	HomePageURL string `json:"home_page_url"`
-	Description string `json:"description"`
-	Language    string `json:"language"`
-	Items       []struct {
-		ID            string    `json:"id"`
-		URL           string    `json:"url"`
-		Title         string    `json:"title"`
-		ContentHTML   string    `json:"content_html"`
-		DatePublished time.Time `json:"date_published"` // 使用 time.Time 类型来解析日期
-		Authors       []struct {
-			Name string `json:"name"`
+func (widget *bilibiliVideosWidget) feedUrls() []string {
+	feedUrls := make([]string, len(widget.RSSHubUrls))
+
+	for i := range widget.RSSHubUrls {
+		feedUrls[i] = widget.RSSHubUrls[i].URL
+
+		if widget.RewriteHost != "" {
+			// validated during initialization so only the feed URL can fail here
+			if rewritten, err := rewriteURLHost(feedUrls[i], widget.RewriteHost); err == nil {
+				feedUrls[i] = rewritten
+			}
 		}
 	}
+
+	return feedUrls
 }
 
-type bilibiliVideo struct {
-	ThumbnailUrl string
-	Title        string
-	Url          string
-	Author       string
-	AuthorUrl    string
-	TimePosted   time.Time
+// The options feeds get fetched and parsed with, shared by updates and the
+// debug endpoint so that the latter shows what updates would end up with
+func (widget *bilibiliVideosWidget) fetchOptions(ctx context.Context, feedUrls []string) bilibiliFetchOptions {
+	return bilibiliFetchOptions{
+		FeedUrls:            feedUrls,
+		VideoUrlTemplate:    widget.VideoUrlTemplate,
+		IncludeShorts:       widget.IncludeShorts,
+		ImageProxy:          widget.ImageProxy,
+		RequireThumbnail:    widget.RequireThumbnail,
+		MinDuration:         time.Duration(widget.MinDuration),
+		DropUnknownDuration: widget.DropUnknownDuration,
+		AuthorUrlTemplate:   widget.AuthorUrlTemplate,
+		AvatarUrlTemplate:   widget.AvatarUrlTemplate,
+		Tolerant:            widget.Tolerant,
+		BlockAuthors:        widget.BlockAuthors,
+		ExtensionFields:     widget.ExtensionFields,
+		AuthorAliases:       widget.AuthorAliases,
+		Client:              widget.client,
+		MaxFeedItems:        widget.MaxFeedItems,
+		MaxFeedBytes:        widget.MaxFeedBytes,
+		HoverPreview:        widget.HoverPreview,
+		ValidateItems:       widget.ValidateItems,
+		Aggregator:          widget.Aggregator,
+		Concurrency:         &widget.metrics.concurrency,
+		ConcurrencyLimit:    widget.concurrencyLimit,
+		Context:             ctx,
+	}
 }
 
-type bilibiliVideoList []bilibiliVideo
+// Created when first needed rather than during initialization since it
+// depends on global options that are only applied after widgets get initialized
+func (widget *bilibiliVideosWidget) ensureClient() {
+	if widget.client != nil {
+		return
+	}
 
-func (v bilibiliVideoList) sortByNewest() bilibiliVideoList {
-	sort.Slice(v, func(i, j int) bool {
-		return v[i].TimePosted.After(v[j].TimePosted)
-	})
+	if widget.DNSCache > 0 {
+		widget.client = newDNSCache(time.Duration(widget.DNSCache)).newHTTPClient()
+	}
 
-	return v
+	if widget.MaxRedirects > 0 {
+		client := defaultHTTPClient
+		if widget.client != nil {
+			client = widget.client
+		}
+
+		widget.client = withMaxRedirects(client, widget.MaxRedirects)
+	}
+
+	if widget.RequestTimeout > 0 {
+		client := *defaultHTTPClient
+		if widget.client != nil {
+			client = *widget.client
+		}
+
+		client.Timeout = time.Duration(widget.RequestTimeout)
+		widget.client = &client
+	}
+
+	if widget.TranscodeCharsets {
+		client := defaultHTTPClient
+		if widget.client != nil {
+			client = widget.client
+		}
+
+		widget.client = withCharsetTranscoding(client)
+	}
+
+	if widget.ConditionalRequests {
+		client := defaultHTTPClient
+		if widget.client != nil {
+			client = widget.client
+		}
+
+		widget.client, widget.conditional = withConditionalRequests(client)
+	}
 }
 
-func fetchBilibiliChannelUploads(RSSHubUrls []string, videoUrlTemplate string, includeShorts bool, imageProxy string) (bilibiliVideoList, error) {
-	requests := make([]*http.Request, 0, len(RSSHubUrls))
+func (widget *bilibiliVideosWidget) feedDebugRequest(index int) (*feedDebugRequest, error) {
+	if index < 0 || index >= len(widget.RSSHubUrls) {
+		return nil, errFeedIndexOutOfRange
+	}
+
+	widget.ensureClient()
 
-	for _, feedUrl := range RSSHubUrls {
-		request, _ := http.NewRequest("GET", feedUrl, nil)
-		requests = append(requests, request)
+	client := defaultHTTPClient
+	if widget.client != nil {
+		client = widget.client
 	}
 
-	job := newJob(decodeJsonFromRequestTask[bilibiliFeedResponseJson](defaultHTTPClient), requests).withWorkers(30)
-	responses, errs, err := workerPoolDo(job)
+	// a 304 would leave nothing to show
+	if widget.conditional != nil {
+		unconditional := *client
+		unconditional.Transport = widget.conditional.base
+		client = &unconditional
+	}
+
+	feedUrl := widget.feedUrls()[index]
+	request, err := http.NewRequest("GET", feedUrl, nil)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", errNoContent, err)
+		return nil, err
 	}
 
-	videos := make(bilibiliVideoList, 0, len(RSSHubUrls)*15)
-	var failed int
+	options := widget.fetchOptions(context.Background(), []string{feedUrl})
+	feed := widget.RSSHubUrls[index]
 
-	for i := range responses {
-		if errs[i] != nil {
-			failed++
-			slog.Error("Failed to fetch youtube feed", "rsshub url", RSSHubUrls[i], "error", errs[i])
+	return &feedDebugRequest{
+		client:  client,
+		request: request,
+		parse: func(body []byte) (any, error) {
+			var parsed bilibiliFeedResponseJson
+			if err := json.Unmarshal(body, &parsed); err != nil {
+				return nil, err
+			}
+
+			videos := bilibiliVideosFromFeed(options, feedUrl, &parsed)
+			feed.transform(videos)
+
+			return videos, nil
+		},
+		health: func() (float64, bool) {
+			return widget.feedHealthPercentage(feedUrl)
+		},
+	}, nil
+}
+
+// Fetches the videos of all feeds except for those with their own cache
+// duration that were fetched recently enough, which are served from the cache
+func (widget *bilibiliVideosWidget) fetchVideos(options bilibiliFetchOptions) (bilibiliVideoList, error) {
+	results := make([]bilibiliVideoList, len(options.FeedUrls))
+	errs := make([]error, len(options.FeedUrls))
+	toFetch := make([]int, 0, len(options.FeedUrls))
+	now := time.Now()
+
+	// only stays this way if fetching fails altogether
+	widget.failedFeeds = len(options.FeedUrls)
+	widget.sequenceGaps = nil
+
+	for i := range options.FeedUrls {
+		feedCache := time.Duration(widget.RSSHubUrls[i].Cache)
+		entry, exists := widget.feedCache[options.FeedUrls[i]]
+
+		if feedCache > 0 && exists && now.Sub(entry.fetchedAt) < feedCache {
+			results[i] = entry.videos
 			continue
 		}
 
-		response := responses[i]
+		toFetch = append(toFetch, i)
+	}
+
+	// dispatching important feeds first makes them more likely to be fetched
+	// before the deadline when there are more feeds than workers
+	slices.SortStableFunc(toFetch, func(a, b int) int {
+		return cmp.Compare(widget.RSSHubUrls[b].Priority, widget.RSSHubUrls[a].Priority)
+	})
+
+	notModified := 0
+
+	if len(toFetch) > 0 {
+		if err := fetchBilibiliFeedsInto(options, toFetch, results, errs); err != nil {
+			return nil, fmt.Errorf("%w: %v", errNoContent, err)
+		}
+
+		widget.transformFetchedFeeds(toFetch, results, errs)
+		widget.fetchFurtherPages(options, toFetch, results, errs)
+		notModified = widget.useCachedUnmodifiedFeeds(options.FeedUrls, toFetch, results, errs)
+
+		// a single empty feed can be genuinely empty, but when every feed comes
+		// back empty it's more likely that RSSHub had a hiccup
+		if widget.RetryOnEmpty && areAllBilibiliFeedsEmpty(results, errs) {
+			time.Sleep(bilibiliRetryOnEmptyDelay)
+
+			if err := fetchBilibiliFeedsInto(options, toFetch, results, errs); err != nil {
+				return nil, fmt.Errorf("%w: %v", errNoContent, err)
+			}
+
+			widget.transformFetchedFeeds(toFetch, results, errs)
+			widget.fetchFurtherPages(options, toFetch, results, errs)
+			notModified = widget.useCachedUnmodifiedFeeds(options.FeedUrls, toFetch, results, errs)
+		}
+
+		if widget.SkipBacklog {
+			widget.skipBacklogs(options.FeedUrls, toFetch, results, errs)
+		}
+
+		for _, i := range toFetch {
+			if errs[i] != nil {
+				continue
+			}
 
-		for j := range response.Items {
-			v := &response.Items[j]
-			// 编译正则表达式
-			re := regexp.MustCompile(`<img[^>]+src="([^"]+)"`)
-			// 查找所有匹配项
-			matches := re.FindAllStringSubmatch(v.ContentHTML, -1)
-			// 提取图像 URL
-			imageURLs := make([]string, 0, len(matches))
-			for _, match := range matches {
-				if len(match) > 1 {
-					imageURLs = append(imageURLs, match[1])
+			if widget.RSSHubUrls[i].Cache > 0 || widget.conditional != nil {
+				widget.feedCache[options.FeedUrls[i]] = bilibiliFeedCacheEntry{
+					videos:    results[i],
+					fetchedAt: now,
 				}
 			}
-			authorNames := make([]string, len(v.Authors))
-			for i, author := range v.Authors {
-				authorNames[i] = author.Name
+
+			if widget.conditional != nil {
+				widget.conditional.commit(options.FeedUrls[i])
 			}
+		}
+
+		for _, i := range toFetch {
+			widget.recordFeedOutcome(options.FeedUrls[i], errs[i])
+		}
+
+		widget.sequenceGaps = widget.detectSequenceGaps(options.FeedUrls, toFetch, results, errs)
+	}
+
+	widget.feedsUnchanged = notModified == len(toFetch)
+
+	widget.failedFeeds = 0
+	for i := range errs {
+		if errs[i] != nil {
+			widget.failedFeeds++
+		}
+	}
 
-			videos = append(videos, bilibiliVideo{
-				ThumbnailUrl: imageProxy + imageURLs[0],
-				Title:        v.Title,
-				Url:          v.URL,
-				Author:       strings.Join(authorNames, ", "),
-				AuthorUrl:    v.URL,
-				TimePosted:   v.DatePublished,
-			})
+	// done on every update rather than when fetching so that videos served
+	// from the cache still age out of the window
+	for i := range results {
+		if window := time.Duration(widget.RSSHubUrls[i].PublishedWithin); window > 0 {
+			results[i] = results[i].publishedAfter(now.Add(-window))
 		}
 	}
 
-	if len(videos) == 0 {
-		return nil, errNoContent
+	for i := range results {
+		for j := range results[i] {
+			results[i][j].Weight = widget.RSSHubUrls[i].Weight
+			results[i][j].feedIndex = i
+		}
 	}
 
-	videos.sortByNewest()
+	return mergeBilibiliFeedResults(results, errs, widget.Order == "feed")
+}
 
-	if failed > 0 {
-		return videos, fmt.Errorf("%w: missing videos from %d channels", errPartialContent, failed)
+// Leaves out the videos posted before the newest video of each feed at the
+// time it was first fetched
+func (widget *bilibiliVideosWidget) skipBacklogs(feedUrls []string, indices []int, results []bilibiliVideoList, errs []error) {
+	if widget.backlogBaselines == nil {
+		widget.backlogBaselines = make(map[string]time.Time)
 	}
 
-	return videos, nil
+	for _, i := range indices {
+		if errs[i] != nil {
+			continue
+		}
+
+		baseline, exists := widget.backlogBaselines[feedUrls[i]]
+		if !exists {
+			for j := range results[i] {
+				if results[i][j].TimePosted.After(baseline) {
+					baseline = results[i][j].TimePosted
+				}
+			}
+
+			// an empty feed has no backlog to skip yet
+			if baseline.IsZero() {
+				continue
+			}
+
+			widget.backlogBaselines[feedUrls[i]] = baseline
+		}
+
+		kept := make(bilibiliVideoList, 0, len(results[i]))
+		for j := range results[i] {
+			if !results[i][j].TimePosted.Before(baseline) {
+				kept = append(kept, results[i][j])
+			}
+		}
+
+		results[i] = kept
+	}
+}
+
+var bilibiliItemSequencePattern = regexp.MustCompile(`(\d+)\D*$`)
+
+func bilibiliItemSequence(id string) int64 {
+	match := bilibiliItemSequencePattern.FindStringSubmatch(id)
+	if match == nil {
+		return 0
+	}
+
+	sequence, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return sequence
+}
+
+// Compares the sequence numbers of the videos of feeds that detect gaps with
+// the highest one seen in previous updates and describes every feed where
+// some of the numbers in between never showed up
+func (widget *bilibiliVideosWidget) detectSequenceGaps(feedUrls []string, indices []int, results []bilibiliVideoList, errs []error) []string {
+	var gaps []string
+
+	for _, i := range indices {
+		if errs[i] != nil || !widget.RSSHubUrls[i].DetectGaps {
+			continue
+		}
+
+		if widget.lastSeenSequence == nil {
+			widget.lastSeenSequence = make(map[string]int64)
+		}
+
+		lastSeen, seenBefore := widget.lastSeenSequence[feedUrls[i]]
+		newest := lastSeen
+		seen := make(map[int64]struct{})
+
+		for j := range results[i] {
+			sequence := results[i][j].sequence
+			if sequence <= lastSeen {
+				continue
+			}
+
+			seen[sequence] = struct{}{}
+			newest = max(newest, sequence)
+		}
+
+		widget.lastSeenSequence[feedUrls[i]] = newest
+
+		// there's nothing to compare against on the first update
+		if !seenBefore || lastSeen == 0 {
+			continue
+		}
+
+		if missing := newest - lastSeen - int64(len(seen)); missing > 0 {
+			gaps = append(gaps, fmt.Sprintf(
+				"possible gap in %s: %d items between #%d and #%d were never seen",
+				feedUrls[i], missing, lastSeen, newest,
+			))
+		}
+	}
+
+	return gaps
+}
+
+// Replaces the errors of feeds that haven't been modified since they were last
+// fetched with their previous videos, returning how many of them there were
+func (widget *bilibiliVideosWidget) useCachedUnmodifiedFeeds(feedUrls []string, indices []int, results []bilibiliVideoList, errs []error) int {
+	notModified := 0
+
+	for _, i := range indices {
+		if !errors.Is(errs[i], errNotModified) {
+			continue
+		}
+
+		if entry, exists := widget.feedCache[feedUrls[i]]; exists {
+			results[i] = entry.videos
+			errs[i] = nil
+			notModified++
+		}
+	}
+
+	return notModified
+}
+
+func (widget *bilibiliVideosWidget) setPageSeenSet(seen map[string]struct{}) {
+	if widget.DedupScope == "page" {
+		widget.pageSeen = seen
+	}
+}
+
+// The featured video followed by the rest, in the order they're shown in
+func (widget *bilibiliVideosWidget) shownVideos() bilibiliVideoList {
+	if widget.FeaturedVideo == nil {
+		return widget.Videos
+	}
+
+	return append(bilibiliVideoList{*widget.FeaturedVideo}, widget.Videos...)
+}
+
+func (widget *bilibiliVideosWidget) preloadImages() []preloadImage {
+	videos := widget.shownVideos()
+	images := make([]preloadImage, 0, len(videos))
+
+	for i := range videos {
+		thumbnailUrl := videos[i].ThumbnailUrl
+
+		// embedded images have nothing to load
+		if thumbnailUrl == "" || strings.HasPrefix(thumbnailUrl, "data:") {
+			continue
+		}
+
+		image := preloadImage{URL: thumbnailUrl}
+
+		// the thumbnails of the list don't get a srcset
+		if widget.Style != "vertical-list" {
+			image.Srcset = videos[i].ThumbnailSrcset
+			image.Sizes = videos[i].ThumbnailSizes
+		}
+
+		images = append(images, image)
+	}
+
+	return images
+}
+
+func (widget *bilibiliVideosWidget) atomFeed() (string, []atomFeedEntry) {
+	videos := widget.shownVideos()
+	entries := make([]atomFeedEntry, len(videos))
+
+	for i := range videos {
+		entries[i] = atomFeedEntry{
+			Title:     videos[i].Title,
+			URL:       videos[i].Url,
+			Author:    videos[i].Author,
+			Published: videos[i].TimePosted,
+		}
+	}
+
+	return widget.Title, entries
+}
+
+func (widget *bilibiliVideosWidget) tracksClicks() bool {
+	return widget.TrackClicks
+}
+
+func (widget *bilibiliVideosWidget) isShowingUrl(url string) bool {
+	for _, videos := range []bilibiliVideoList{widget.shownVideos(), widget.ArchivedVideos} {
+		for i := range videos {
+			if videos[i].Url == url || videos[i].AuthorUrl == url || videos[i].SourceUrl == url {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (widget *bilibiliVideosWidget) Render() template.HTML {
+	var template *template.Template
+
+	if widget.pageSeen != nil {
+		videos := widget.Videos
+		defer func() { widget.Videos = videos }()
+
+		widget.Videos = videos.notIn(widget.pageSeen)
+	}
+
+	if widget.MinItems > 0 && widget.ContentAvailable && len(widget.shownVideos()) < widget.MinItems {
+		return ""
+	}
+
+	if widget.pageSeen != nil {
+		for i := range widget.Videos {
+			widget.pageSeen[widget.Videos[i].Url] = struct{}{}
+		}
+	}
+
+	if widget.renderCache != "" {
+		return widget.renderCache
+	}
+
+	switch {
+	case widget.Group == "author-carousel":
+		template = bilibiliVideosWidgetAuthorCarouselTemplate
+	case widget.Style == "grid-cards":
+		template = bilibiliVideosWidgetGridTemplate
+	case widget.Style == "vertical-list":
+		template = bilibiliVideosWidgetVerticalListTemplate
+	default:
+		template = bilibiliVideosWidgetTemplate
+	}
+
+	rendered := widget.renderTemplate(widget, template)
+
+	if widget.canReuseRender() && widget.ContentAvailable && widget.Error == nil {
+		widget.renderCache = rendered
+	}
+
+	return rendered
+}
+
+// Whether what gets rendered only changes when the videos do, and not with the
+// passing of time
+func (widget *bilibiliVideosWidget) canReuseRender() bool {
+	return widget.ConditionalRequests &&
+		widget.DedupScope != "page" &&
+		!widget.ShowCountdown &&
+		widget.ArchiveAfter == 0 &&
+		widget.GroupBy == "" &&
+		// the relative time gets computed when rendering
+		!strings.Contains(widget.AriaLabelFormat, "{time}") &&
+		// videos age out of the window even when their feed hasn't changed
+		!slices.ContainsFunc(widget.RSSHubUrls, func(feed bilibiliFeedField) bool {
+			return feed.PublishedWithin > 0
+		})
+}
+
+// Replaces the host of the given URL while preserving its path and query, the
+// new host can optionally include a scheme, in which case it's replaced too
+func rewriteURLHost(rawURL string, host string) (string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if scheme, rest, found := strings.Cut(host, "://"); found {
+		parsedURL.Scheme = scheme
+		host = rest
+	}
+
+	host = strings.TrimRight(host, "/")
+	if host == "" || strings.ContainsAny(host, "/?#") {
+		return "", fmt.Errorf("%q is not a valid host", host)
+	}
+
+	parsedURL.Host = host
+
+	return parsedURL.String(), nil
+}
+
+type bilibiliVideo struct {
+	ThumbnailUrl         string
+	DirectThumbnailUrl   string
+	FallbackThumbnailUrl string
+	FallbackTimeoutMs    int64
+	DefaultThumbnailUrl  string
+	ThumbnailSrcset      string
+	ThumbnailSizes       string
+	Title                string
+	Url                  string
+	Author               string
+	AuthorUrl            string
+	SourceUrl            string
+	TimePosted           time.Time
+	Duration             time.Duration
+	// either live, premiere or empty for regular videos
+	Status          string
+	AuthorAvatarUrl string
+	// whether a video that comes before it has the same thumbnail
+	LikelyDuplicate  bool
+	SourceIconUrl    string
+	SourceIconIsFlat bool
+	Extra            map[string]any
+	ExtraLabels      []string
+	Weight           float64
+	SourceLabel      string
+	Footer           string
+	AppUrl           string
+	AuthorColor      template.CSS
+	ThumbnailColor   template.CSS
+	PreviewUrl       string
+	feedIndex        int
+	// the number at the end of the item's ID, 0 if there isn't one
+	sequence int64
+	// the time it was posted had no offset and was interpreted as UTC
+	dateHasNoOffset bool
+	ariaLabelFormat string
+	location        *time.Location
+	// the width times the height of the thumbnail, 0 if unknown
+	thumbnailPixels int
+}
+
+// Gets called when rendering rather than when updating so that the relative
+// time is accurate
+func (v *bilibiliVideo) AriaLabel() string {
+	if v.ariaLabelFormat == "" {
+		return v.Title
+	}
+
+	location := v.location
+	if location == nil {
+		location = time.Local
+	}
+
+	return strings.NewReplacer(
+		"{title}", v.Title,
+		"{author}", v.Author,
+		"{time}", formatRelativeTime(v.TimePosted, time.Now()),
+		"{date}", v.TimePosted.In(location).Format("Jan 2, 2006"),
+	).Replace(v.ariaLabelFormat)
+}
+
+func formatRelativeTime(t time.Time, now time.Time) string {
+	elapsed := now.Sub(t)
+
+	// scheduled videos and clocks that are ahead can put the time in the future
+	future := elapsed < 0
+	if future {
+		elapsed = -elapsed
+	}
+
+	plural := func(value int, unit string) string {
+		text := strconv.Itoa(value) + " " + unit
+		if value != 1 {
+			text += "s"
+		}
+
+		if future {
+			return "in " + text
+		}
+
+		return text + " ago"
+	}
+
+	switch {
+	case t.IsZero():
+		return ""
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		return plural(int(elapsed/time.Minute), "minute")
+	case elapsed < 24*time.Hour:
+		return plural(int(elapsed/time.Hour), "hour")
+	case elapsed < 30*24*time.Hour:
+		return plural(int(elapsed/(24*time.Hour)), "day")
+	case elapsed < 365*24*time.Hour:
+		return plural(int(elapsed/(30*24*time.Hour)), "month")
+	default:
+		return plural(int(elapsed/(365*24*time.Hour)), "year")
+	}
+}
+
+func (v *bilibiliVideo) withExtensionFields(fields map[string]any, order []string) {
+	v.Extra = fields
+
+	for _, path := range order {
+		value, exists := fields[path]
+		if !exists {
+			continue
+		}
+
+		var label string
+		switch value := value.(type) {
+		case string:
+			label = value
+		case float64:
+			label = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			label = strconv.FormatBool(value)
+		default:
+			// objects and arrays can't be shown as a label
+			continue
+		}
+
+		if label != "" {
+			v.ExtraLabels = append(v.ExtraLabels, label)
+		}
+	}
+}
+
+type bilibiliVideoList []bilibiliVideo
+
+var bilibiliVideoIDPattern = regexp.MustCompile(`BV[0-9A-Za-z]{10}`)
+
+// Checks that the video has everything needed to display it
+func (v *bilibiliVideo) validate() error {
+	if strings.TrimSpace(v.Title) == "" {
+		return errors.New("missing title")
+	}
+
+	if v.Url == "" {
+		return errors.New("missing url")
+	}
+
+	if parsed, err := url.Parse(v.Url); err != nil || !parsed.IsAbs() {
+		return fmt.Errorf("url %q is not absolute", v.Url)
+	}
+
+	if v.TimePosted.IsZero() {
+		return errors.New("missing date")
+	}
+
+	return nil
+}
+
+// Returns the URL that opens the video in the bilibili app, or an empty
+// string if the video's ID can't be found in its URL
+func bilibiliAppUrl(videoUrl string) string {
+	id := bilibiliVideoIDPattern.FindString(videoUrl)
+	if id == "" {
+		return ""
+	}
+
+	return "bilibili://video/" + id
+}
+
+type authorColorsField struct {
+	enabled bool
+	colors  map[string]*hslColorField
+}
+
+func (f *authorColorsField) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err == nil {
+		if value != "auto" {
+			return fmt.Errorf("line %d: author-colors must be either auto or a map of authors to colors", node.Line)
+		}
+
+		f.enabled = true
+		return nil
+	}
+
+	if err := node.Decode(&f.colors); err != nil {
+		return err
+	}
+
+	f.enabled = true
+	return nil
+}
+
+// The same author always gets the same color, regardless of which
+// feed their videos came from or when they were fetched
+func (f *authorColorsField) colorFor(author string) *hslColorField {
+	if color, exists := f.colors[author]; exists {
+		return color
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(author))
+
+	return &hslColorField{
+		Hue:        uint16(hash.Sum32() % (hslHueMax + 1)),
+		Saturation: 60,
+		Lightness:  55,
+	}
+}
+
+func (v bilibiliVideoList) withAuthorColors(colors *authorColorsField) {
+	for i := range v {
+		v[i].AuthorColor = template.CSS(colors.colorFor(v[i].Author).String())
+	}
+}
+
+func (v bilibiliVideoList) withAriaLabelFormat(format string, location *time.Location) {
+	for i := range v {
+		v[i].ariaLabelFormat = format
+		v[i].location = location
+	}
+}
+
+// Moves the newest video of the author to the front while keeping the order
+// of everything else, the videos aren't necessarily sorted by newest
+func (v bilibiliVideoList) moveNewestByAuthorToFront(author string) {
+	newest := -1
+
+	for i := range v {
+		if strings.EqualFold(v[i].Author, author) && (newest == -1 || v[i].TimePosted.After(v[newest].TimePosted)) {
+			newest = i
+		}
+	}
+
+	if newest <= 0 {
+		return
+	}
+
+	featured := v[newest]
+	copy(v[1:newest+1], v[:newest])
+	v[0] = featured
+}
+
+func (v bilibiliVideoList) withAppUrls() {
+	for i := range v {
+		v[i].AppUrl = bilibiliAppUrl(v[i].Url)
+	}
+}
+
+var bilibiliFooterTokenPattern = regexp.MustCompile(`\{(author|date|duration|source)\}`)
+
+func (v bilibiliVideoList) withFooter(format string, location *time.Location) {
+	for i := range v {
+		v[i].Footer = formatBilibiliFooter(format, map[string]string{
+			"author":   v[i].Author,
+			"date":     v[i].TimePosted.In(location).Format("Jan 2"),
+			"duration": formatVideoDuration(v[i].Duration),
+			"source":   v[i].SourceLabel,
+		})
+	}
+}
+
+// Formats the duration as m:ss or h:mm:ss, unknown durations are left empty
+func formatVideoDuration(duration time.Duration) string {
+	if duration <= 0 {
+		return ""
+	}
+
+	seconds := int(duration.Round(time.Second).Seconds())
+
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// Replaces the tokens in the format with their values, dropping the text
+// between a missing value and its neighbours so that no dangling separators
+// are left behind
+func formatBilibiliFooter(format string, values map[string]string) string {
+	var footer strings.Builder
+	var separator string
+	skippedLeading := false
+	skippedLast := false
+	last := 0
+
+	for _, match := range bilibiliFooterTokenPattern.FindAllStringSubmatchIndex(format, -1) {
+		separator += format[last:match[0]]
+		last = match[1]
+
+		value := values[format[match[2]:match[3]]]
+		skippedLast = value == ""
+
+		if skippedLast {
+			separator = ""
+			skippedLeading = skippedLeading || footer.Len() == 0
+			continue
+		}
+
+		if footer.Len() > 0 || !skippedLeading {
+			footer.WriteString(separator)
+		}
+
+		footer.WriteString(value)
+		separator = ""
+	}
+
+	if footer.Len() > 0 && !skippedLast {
+		footer.WriteString(format[last:])
+	}
+
+	return strings.TrimSpace(footer.String())
+}
+
+func (v bilibiliVideoList) sortByNewest() bilibiliVideoList {
+	sort.Slice(v, func(i, j int) bool {
+		return v[i].TimePosted.After(v[j].TimePosted)
+	})
+
+	return v
+}
+
+// Reinterprets the times that had no offset as being in the given location
+func (v bilibiliVideoList) withSourceLocation(location *time.Location) {
+	for i := range v {
+		if !v[i].dateHasNoOffset {
+			continue
+		}
+
+		t := v[i].TimePosted
+		v[i].TimePosted = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), location)
+		v[i].dateHasNoOffset = false
+	}
+}
+
+// Moves live streams and premieres to the front while keeping the order of
+// everything else
+func (v bilibiliVideoList) moveLiveToFront() {
+	sort.SliceStable(v, func(i, j int) bool {
+		return v[i].Status != "" && v[j].Status == ""
+	})
+}
+
+// Sorts by the weight of each video's feed, decayed by half for every
+// half-life that has passed since the video was posted
+func (v bilibiliVideoList) sortByWeightedRecency(halfLife time.Duration, now time.Time) bilibiliVideoList {
+	score := func(video *bilibiliVideo) float64 {
+		halfLives := now.Sub(video.TimePosted).Hours() / halfLife.Hours()
+		return video.Weight * math.Pow(0.5, halfLives)
+	}
+
+	sort.SliceStable(v, func(i, j int) bool {
+		return score(&v[i]) > score(&v[j])
+	})
+
+	return v
+}
+
+// Matches the widths at which the page's layout changes, mobile being up to
+// 550px wide and tablet up to 1190px
+type bilibiliBreakpointLimits struct {
+	Mobile  int `yaml:"mobile"`
+	Tablet  int `yaml:"tablet"`
+	Desktop int `yaml:"desktop"`
+}
+
+// Keeps at most the given number of videos from each author for every day,
+// preferring the ones that come first in the list
+func (v bilibiliVideoList) capPerAuthorPerDay(limit int, location *time.Location) bilibiliVideoList {
+	type authorDay struct {
+		author string
+		day    string
+	}
+
+	counts := make(map[authorDay]int)
+	capped := make(bilibiliVideoList, 0, len(v))
+
+	for i := range v {
+		key := authorDay{
+			author: v[i].Author,
+			day:    v[i].TimePosted.In(location).Format(time.DateOnly),
+		}
+
+		if counts[key] >= limit {
+			continue
+		}
+
+		counts[key]++
+		capped = append(capped, v[i])
+	}
+
+	return capped
+}
+
+// Returns the videos posted after the given time, leaving the list as is
+func (v bilibiliVideoList) publishedAfter(cutoff time.Time) bilibiliVideoList {
+	kept := make(bilibiliVideoList, 0, len(v))
+
+	for i := range v {
+		if v[i].TimePosted.After(cutoff) {
+			kept = append(kept, v[i])
+		}
+	}
+
+	return kept
+}
+
+// Splits the list into videos posted within the given duration and older
+// videos, preserving the order of both
+func (v bilibiliVideoList) partitionByAge(maxAge time.Duration) (bilibiliVideoList, bilibiliVideoList) {
+	cutoff := time.Now().Add(-maxAge)
+	recent := make(bilibiliVideoList, 0, len(v))
+	older := make(bilibiliVideoList, 0)
+
+	for i := range v {
+		if v[i].TimePosted.Before(cutoff) {
+			older = append(older, v[i])
+		} else {
+			recent = append(recent, v[i])
+		}
+	}
+
+	return recent, older
+}
+
+type bilibiliFeedField struct {
+	URL   string        `yaml:"url"`
+	Cache durationField `yaml:"cache"`
+	// feeds with a higher priority get fetched first
+	Priority int `yaml:"priority"`
+	// only used when sorting by weight, defaults to 1
+	Weight float64 `yaml:"weight"`
+	// applied in order to every video of the feed
+	Transforms []bilibiliVideoTransform `yaml:"transforms"`
+	// for feeds whose item IDs end in a number that goes up by one with every
+	// item, shows a notice when items seem to have been missed between updates
+	DetectGaps bool `yaml:"detect-gaps"`
+	// the timezone dates without an offset are in, UTC when not set
+	SourceTimezone string `yaml:"source-timezone"`
+	// leaves out the videos of the feed posted longer ago than this
+	PublishedWithin durationField `yaml:"published-within"`
+	// keeps the feed in the config without fetching it
+	Disabled bool `yaml:"disabled"`
+
+	sourceLocation *time.Location
+}
+
+func (f *bilibiliFeedField) UnmarshalYAML(node *yaml.Node) error {
+	type bilibiliFeedFieldAlias bilibiliFeedField
+	alias := (*bilibiliFeedFieldAlias)(f)
+
+	if err := node.Decode(&f.URL); err != nil {
+		if err := node.Decode(alias); err != nil {
+			return err
+		}
+	}
+
+	if f.URL == "" {
+		return errors.New("feed URL is required")
+	}
+
+	if f.Weight <= 0 {
+		f.Weight = 1
+	}
+
+	if f.SourceTimezone != "" {
+		location, err := time.LoadLocation(f.SourceTimezone)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid source-timezone: %v", node.Line, err)
+		}
+
+		f.sourceLocation = location
+	}
+
+	return nil
+}
+
+var bilibiliRetryOnEmptyDelay = 3 * time.Second
+
+type bilibiliFeedCacheEntry struct {
+	videos    bilibiliVideoList
+	fetchedAt time.Time
 }
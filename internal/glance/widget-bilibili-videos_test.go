@@ -0,0 +1,1820 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func newTestBilibiliVideosWidget(t *testing.T, config string) *bilibiliVideosWidget {
+	t.Helper()
+
+	widget := &bilibiliVideosWidget{}
+	if err := yaml.Unmarshal([]byte("type: bilibili-videos\n"+config), widget); err != nil {
+		t.Fatalf("unmarshaling config: %v", err)
+	}
+
+	if err := widget.initialize(); err != nil {
+		t.Fatalf("initializing widget: %v", err)
+	}
+
+	widget.setProviders(&widgetProviders{
+		assetResolver: func(asset string) string { return "/static/" + asset },
+	})
+
+	return widget
+}
+
+func TestBilibiliVideosAriaLabelStaysCurrentAcrossRenders(t *testing.T) {
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls: [https://rsshub.example/bilibili/user/video/1]
+conditional-requests: true
+`)
+
+	widget.ContentAvailable = true
+	widget.Videos = bilibiliVideoList{{
+		Title:      "First video",
+		Url:        "https://www.bilibili.com/video/BV1xx411c7mD",
+		Author:     "Someone",
+		TimePosted: time.Now().Add(-5 * time.Minute),
+	}}
+	widget.Videos.withAriaLabelFormat(widget.AriaLabelFormat, widget.location)
+
+	first := string(widget.Render())
+	if !strings.Contains(first, "First video by Someone, 5 minutes ago") {
+		t.Fatalf("expected the first render to contain the relative time, got:\n%s", first)
+	}
+
+	// the same as time passing by between the two renders
+	widget.Videos[0].TimePosted = widget.Videos[0].TimePosted.Add(-2 * time.Hour)
+
+	second := string(widget.Render())
+	if !strings.Contains(second, "First video by Someone, 2 hours ago") {
+		t.Fatalf("expected the second render to contain the updated relative time, got:\n%s", second)
+	}
+}
+
+func bilibiliVideoUrls(videos bilibiliVideoList) []string {
+	urls := make([]string, len(videos))
+	for i := range videos {
+		urls[i] = videos[i].Url
+	}
+
+	return urls
+}
+
+func TestBilibiliVideoListSortByWeightedRecency(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		halfLife time.Duration
+		videos   bilibiliVideoList
+		wantUrls []string
+	}{
+		{
+			name:     "equal weights sort by newest",
+			halfLife: 24 * time.Hour,
+			videos: bilibiliVideoList{
+				{Url: "old", TimePosted: now.Add(-48 * time.Hour), Weight: 1},
+				{Url: "new", TimePosted: now.Add(-time.Hour), Weight: 1},
+				{Url: "middle", TimePosted: now.Add(-24 * time.Hour), Weight: 1},
+			},
+			wantUrls: []string{"new", "middle", "old"},
+		},
+		{
+			name:     "weight outlasts a half-life",
+			halfLife: 24 * time.Hour,
+			videos: bilibiliVideoList{
+				{Url: "new", TimePosted: now.Add(-time.Hour), Weight: 1},
+				{Url: "heavy", TimePosted: now.Add(-25 * time.Hour), Weight: 3},
+				{Url: "old", TimePosted: now.Add(-72 * time.Hour), Weight: 1},
+			},
+			wantUrls: []string{"heavy", "new", "old"},
+		},
+		{
+			name:     "recency wins with a short half-life",
+			halfLife: time.Hour,
+			videos: bilibiliVideoList{
+				{Url: "heavy", TimePosted: now.Add(-25 * time.Hour), Weight: 3},
+				{Url: "new", TimePosted: now.Add(-time.Hour), Weight: 1},
+			},
+			wantUrls: []string{"new", "heavy"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sorted := test.videos.sortByWeightedRecency(test.halfLife, now)
+
+			if urls := bilibiliVideoUrls(sorted); !slices.Equal(urls, test.wantUrls) {
+				t.Fatalf("expected %v, got %v", test.wantUrls, urls)
+			}
+		})
+	}
+}
+
+func TestFormatBilibiliFooter(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		values map[string]string
+		want   string
+	}{
+		{
+			name:   "all values",
+			format: "{author} · {date} · {duration}",
+			values: map[string]string{"author": "A", "date": "May 10", "duration": "1:05"},
+			want:   "A · May 10 · 1:05",
+		},
+		{
+			name:   "missing first value",
+			format: "{author} · {date} · {duration}",
+			values: map[string]string{"date": "May 10", "duration": "1:05"},
+			want:   "May 10 · 1:05",
+		},
+		{
+			name:   "missing middle value",
+			format: "{author} · {date} · {duration}",
+			values: map[string]string{"author": "A", "duration": "1:05"},
+			want:   "A · 1:05",
+		},
+		{
+			name:   "missing last value",
+			format: "{author} · {date} · {duration}",
+			values: map[string]string{"author": "A", "date": "May 10"},
+			want:   "A · May 10",
+		},
+		{
+			name:   "surrounding text",
+			format: "by {author}!",
+			values: map[string]string{"author": "A"},
+			want:   "by A!",
+		},
+		{
+			name:   "nothing to show",
+			format: "by {author}!",
+			values: map[string]string{},
+			want:   "",
+		},
+		{
+			name:   "unknown tokens are kept",
+			format: "{author} {unknown}",
+			values: map[string]string{"author": "A"},
+			want:   "A {unknown}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := formatBilibiliFooter(test.format, test.values); got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatVideoDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		want     string
+	}{
+		{0, ""},
+		{-time.Second, ""},
+		{65 * time.Second, "1:05"},
+		{59600 * time.Millisecond, "1:00"},
+		{3725 * time.Second, "1:02:05"},
+	}
+
+	for _, test := range tests {
+		if got := formatVideoDuration(test.duration); got != test.want {
+			t.Errorf("formatVideoDuration(%v): expected %q, got %q", test.duration, test.want, got)
+		}
+	}
+}
+
+func TestBilibiliVideosThumbnailFallbackAttributes(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		video  bilibiliVideo
+		want   []string
+		absent []string
+	}{
+		{
+			name:   "proxied card",
+			config: "image-proxy: https://proxy.example/?url=\nimage-proxy-timeout: 3s\n",
+			video: bilibiliVideo{
+				ThumbnailUrl:       "https://proxy.example/?url=https://i0.hdslb.com/a.jpg",
+				DirectThumbnailUrl: "https://i0.hdslb.com/a.jpg",
+			},
+			want: []string{`data-fallback-src="https://i0.hdslb.com/a.jpg"`, `data-fallback-timeout="3000"`},
+		},
+		{
+			name:   "proxied list",
+			config: "style: vertical-list\nimage-proxy: https://proxy.example/?url=\nimage-proxy-timeout: 2s\n",
+			video: bilibiliVideo{
+				ThumbnailUrl:       "https://proxy.example/?url=https://i0.hdslb.com/a.jpg",
+				DirectThumbnailUrl: "https://i0.hdslb.com/a.jpg",
+			},
+			want: []string{`data-fallback-src="https://i0.hdslb.com/a.jpg"`, `data-fallback-timeout="2000"`},
+		},
+		{
+			// there is nothing to fall back to
+			name:   "direct thumbnail",
+			config: "image-proxy-timeout: 3s\n",
+			video: bilibiliVideo{
+				ThumbnailUrl:       "https://i0.hdslb.com/a.jpg",
+				DirectThumbnailUrl: "https://i0.hdslb.com/a.jpg",
+			},
+			absent: []string{"data-fallback-src", "data-fallback-timeout"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\n"+test.config)
+
+			video := test.video
+			video.Title = "Video"
+			video.Url = "https://www.bilibili.com/video/BV1xx411c7mD"
+			video.TimePosted = time.Now()
+
+			widget.ContentAvailable = true
+			widget.Videos = bilibiliVideoList{video}
+			widget.Videos.withThumbnailFallback(time.Duration(widget.ImageProxyTimeout))
+
+			rendered := string(widget.Render())
+
+			for _, want := range test.want {
+				if !strings.Contains(rendered, want) {
+					t.Errorf("expected the markup to contain %s, got:\n%s", want, rendered)
+				}
+			}
+
+			for _, absent := range test.absent {
+				if strings.Contains(rendered, absent) {
+					t.Errorf("expected the markup not to contain %s, got:\n%s", absent, rendered)
+				}
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosCollapseThreshold(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		videos int
+		want   string
+	}{
+		{
+			// how many rows the cards take up is only known to the page's script
+			name:   "grid with fewer videos than rows",
+			config: "style: grid-cards\ncollapse-after-rows: 4\n",
+			videos: 3,
+			want:   `data-collapse-after-rows="4"`,
+		},
+		{
+			name:   "grid with more videos than rows",
+			config: "style: grid-cards\ncollapse-after-rows: 2\n",
+			videos: 30,
+			want:   `data-collapse-after-rows="2"`,
+		},
+		{
+			name:   "list with fewer videos than the threshold",
+			config: "style: vertical-list\ncollapse-after: 5\n",
+			videos: 3,
+			want:   `data-collapse-after="-1"`,
+		},
+		{
+			name:   "list with as many videos as the threshold",
+			config: "style: vertical-list\ncollapse-after: 5\n",
+			videos: 5,
+			want:   `data-collapse-after="-1"`,
+		},
+		{
+			name:   "list with more videos than the threshold",
+			config: "style: vertical-list\ncollapse-after: 5\n",
+			videos: 6,
+			want:   `data-collapse-after="5"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\n"+test.config)
+
+			widget.ContentAvailable = true
+			for i := range test.videos {
+				widget.Videos = append(widget.Videos, bilibiliVideo{
+					Title:      fmt.Sprintf("Video %d", i),
+					Url:        fmt.Sprintf("https://www.bilibili.com/video/BV%d", i),
+					TimePosted: time.Now(),
+				})
+			}
+
+			rendered := string(widget.Render())
+			if !strings.Contains(rendered, test.want) {
+				t.Errorf("expected the markup to contain %s, got:\n%s", test.want, rendered)
+			}
+		})
+	}
+}
+
+func TestFormatRelativeTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name string
+		time time.Time
+		want string
+	}{
+		{"zero", time.Time{}, ""},
+		{"now", now, "just now"},
+		{"under a minute ago", now.Add(-59 * time.Second), "just now"},
+		{"a minute ago", now.Add(-time.Minute), "1 minute ago"},
+		{"under an hour ago", now.Add(-time.Hour + time.Second), "59 minutes ago"},
+		{"an hour ago", now.Add(-time.Hour), "1 hour ago"},
+		{"hours ago", now.Add(-23 * time.Hour), "23 hours ago"},
+		{"a day ago", now.Add(-day), "1 day ago"},
+		{"under a month ago", now.Add(-29 * day), "29 days ago"},
+		{"a month ago", now.Add(-30 * day), "1 month ago"},
+		{"under a year ago", now.Add(-364 * day), "12 months ago"},
+		{"a year ago", now.Add(-365 * day), "1 year ago"},
+		{"years ago", now.Add(-3 * 365 * day), "3 years ago"},
+		{"under a minute ahead", now.Add(59 * time.Second), "just now"},
+		{"a minute ahead", now.Add(time.Minute), "in 1 minute"},
+		{"hours ahead", now.Add(5 * time.Hour), "in 5 hours"},
+		{"days ahead", now.Add(3 * day), "in 3 days"},
+		{"a year ahead", now.Add(365 * day), "in 1 year"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := formatRelativeTime(test.time, now); got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+// Serves each of the feeds at its path and responds with a 404 to anything else
+func newTestBilibiliFeedServer(t *testing.T, feeds map[string]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		feed, exists := feeds[r.URL.Path]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(feed))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func bilibiliTestFeed(items ...string) string {
+	return `{"items": [` + strings.Join(items, ",") + `]}`
+}
+
+func bilibiliTestItem(id string, title string, published time.Time) string {
+	return fmt.Sprintf(`{
+		"url": "https://www.bilibili.com/video/%s",
+		"title": %q,
+		"content_html": "<img src=\"https://i0.hdslb.com/%s.jpg\">",
+		"date_published": %q,
+		"authors": [{"name": "Author"}]
+	}`, id, title, id, published.Format(time.RFC3339))
+}
+
+func TestBilibiliVideosArchiveAfter(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": bilibiliTestFeed(
+			bilibiliTestItem("BV1", "Recent video", time.Now().Add(-time.Hour)),
+			bilibiliTestItem("BV2", "Old video", time.Now().Add(-40*24*time.Hour)),
+		),
+	})
+
+	widget := newTestBilibiliVideosWidget(t, "rsshuburls: ["+server.URL+"/feed]\narchive-after: 30d\n")
+	widget.update(context.Background())
+
+	if len(widget.Videos) != 1 || widget.Videos[0].Title != "Recent video" {
+		t.Fatalf("expected only the recent video to be shown, got %v", bilibiliVideoUrls(widget.Videos))
+	}
+
+	if len(widget.ArchivedVideos) != 1 || widget.ArchivedVideos[0].Title != "Old video" {
+		t.Fatalf("expected the old video to be archived, got %v", bilibiliVideoUrls(widget.ArchivedVideos))
+	}
+
+	rendered := string(widget.Render())
+	archive := strings.Index(rendered, "<details")
+	if archive == -1 {
+		t.Fatalf("expected an archive section, got:\n%s", rendered)
+	}
+
+	if old := strings.Index(rendered, "Old video"); old < archive {
+		t.Error("expected the old video to only be shown in the archive section")
+	}
+
+	if recent := strings.Index(rendered, "Recent video"); recent == -1 || recent > archive {
+		t.Error("expected the recent video to be shown before the archive section")
+	}
+}
+
+func TestBilibiliVideosCardsStyle(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+		absent []string
+	}{
+		{
+			name:   "defaults",
+			want:   []string{"--title-lines: 2;"},
+			absent: []string{"--cards-gap", "--widget-content-horizontal-padding"},
+		},
+		{
+			name:   "configured",
+			config: "gap: 1.5\ncard-padding: 0.75\n",
+			want: []string{
+				"--cards-gap: 1.5rem;",
+				"--widget-content-horizontal-padding: 0.75rem;",
+				"--widget-content-vertical-padding: 0.75rem;",
+			},
+		},
+		{
+			name:   "grid",
+			config: "style: grid-cards\ngap: 2\n",
+			want:   []string{"--cards-gap: 2rem;"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\n"+test.config)
+			widget.ContentAvailable = true
+			widget.Videos = bilibiliVideoList{{Title: "Video", Url: "https://www.bilibili.com/video/BV1", TimePosted: time.Now()}}
+
+			rendered := string(widget.Render())
+
+			for _, want := range test.want {
+				if !strings.Contains(rendered, want) {
+					t.Errorf("expected the markup to contain %s, got:\n%s", want, rendered)
+				}
+			}
+
+			for _, absent := range test.absent {
+				if strings.Contains(rendered, absent) {
+					t.Errorf("expected the markup not to contain %s", absent)
+				}
+			}
+		})
+	}
+}
+
+func TestRewriteURLHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		host    string
+		want    string
+		wantErr bool
+	}{
+		{"host", "https://rsshub.app/bilibili/user/video/1?limit=5", "cache.local:8080", "https://cache.local:8080/bilibili/user/video/1?limit=5", false},
+		{"host with scheme", "https://rsshub.app/bilibili/user/video/1", "http://cache.local/", "http://cache.local/bilibili/user/video/1", false},
+		{"host with path", "https://rsshub.app/a", "cache.local/path", "", true},
+		{"empty host", "https://rsshub.app/a", "http://", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := rewriteURLHost(test.url, test.host)
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected an error: %v, got %v", test.wantErr, err)
+			}
+
+			if got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosRewriteHost(t *testing.T) {
+	var requested []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.Host+r.URL.RequestURI())
+		w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("BV1", "Video", time.Now()))))
+	}))
+	defer server.Close()
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls:
+  - https://rsshub.app/bilibili/user/video/1?limit=5
+rewrite-host: `+server.URL+`
+`)
+	widget.update(context.Background())
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	want := []string{host + "/bilibili/user/video/1?limit=5"}
+
+	if !slices.Equal(requested, want) {
+		t.Errorf("expected the feed to be requested as %v, got %v", want, requested)
+	}
+
+	if len(widget.Videos) != 1 {
+		t.Errorf("expected the video of the rewritten feed, got %d videos", len(widget.Videos))
+	}
+}
+
+func TestBilibiliVideosTitleLines(t *testing.T) {
+	title := strings.Repeat("A rather long title that takes up several lines ", 5)
+
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"default", "", "--title-lines: 2;"},
+		{"configured", "title-lines: 3\n", "--title-lines: 3;"},
+		{"grid", "style: grid-cards\ntitle-lines: 1\n", "--title-lines: 1;"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\n"+test.config)
+			widget.ContentAvailable = true
+			widget.Videos = bilibiliVideoList{{Title: title, Url: "https://www.bilibili.com/video/BV1", TimePosted: time.Now()}}
+
+			rendered := string(widget.Render())
+
+			if !strings.Contains(rendered, test.want) {
+				t.Errorf("expected the markup to contain %s", test.want)
+			}
+
+			// the whole title is there, it's only clamped visually
+			if !strings.Contains(rendered, `class="text-truncate-lines expand-on-hover`) || !strings.Contains(rendered, ">"+title+"</a>") {
+				t.Errorf("expected the full title in an element that expands on hover, got:\n%s", rendered)
+			}
+
+			if !strings.Contains(rendered, `aria-label="`+title) {
+				t.Error("expected screen readers to be given the full title")
+			}
+		})
+	}
+}
+
+var anchorTagPattern = regexp.MustCompile(`<a\b[^>]*\bhref="([^"]*)"|</a>`)
+
+func TestBilibiliVideosSeparateVideoAndAuthorLinks(t *testing.T) {
+	styles := map[string]string{
+		"default":       "",
+		"grid-cards":    "style: grid-cards\n",
+		"vertical-list": "style: vertical-list\n",
+	}
+
+	for name, style := range styles {
+		t.Run(name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\n"+style)
+			widget.ContentAvailable = true
+			widget.Videos = bilibiliVideoList{{
+				Title:        "Video",
+				Url:          "https://www.bilibili.com/video/BV1",
+				ThumbnailUrl: "https://i0.hdslb.com/a.jpg",
+				Author:       "Author",
+				AuthorUrl:    "https://space.bilibili.com/123",
+				TimePosted:   time.Now(),
+			}}
+
+			rendered := string(widget.Render())
+			hrefs := make(map[string]int)
+			open := false
+
+			for _, match := range anchorTagPattern.FindAllStringSubmatch(rendered, -1) {
+				if match[0] == "</a>" {
+					open = false
+					continue
+				}
+
+				if open {
+					t.Fatalf("expected links not to be nested, got:\n%s", rendered)
+				}
+
+				open = true
+				hrefs[match[1]]++
+			}
+
+			if hrefs["https://www.bilibili.com/video/BV1"] == 0 {
+				t.Error("expected a link to the video")
+			}
+
+			if hrefs["https://space.bilibili.com/123"] != 1 {
+				t.Errorf("expected a single link to the author's channel, got %d", hrefs["https://space.bilibili.com/123"])
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosSourceFavicon(t *testing.T) {
+	var homePageRequests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			homePageRequests++
+		}
+
+		w.Write([]byte(`<html><head><link rel="icon" href="/static/icon.png"></head></html>`))
+	}))
+	defer server.Close()
+
+	// a lookup that failed before, which shouldn't be attempted again
+	setCachedFavicon("no-favicon.example", "")
+
+	widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\nshow-source-favicon: true\n")
+	widget.ContentAvailable = true
+
+	for range 2 {
+		widget.Videos = bilibiliVideoList{
+			{Title: "Resolved", Url: server.URL + "/video/1", TimePosted: time.Now()},
+			{Title: "Unresolved", Url: "https://no-favicon.example/video/2", TimePosted: time.Now()},
+		}
+		widget.Videos.withSourceIcons(widget.Providers.assetResolver("icons/bilibili.svg"))
+	}
+
+	if homePageRequests != 1 {
+		t.Errorf("expected the favicon to be resolved once and then cached, got %d requests", homePageRequests)
+	}
+
+	rendered := string(widget.Render())
+
+	for _, want := range []string{
+		`<img class="video-source-icon" src="` + server.URL + `/static/icon.png"`,
+		`<img class="video-source-icon flat-icon" src="/static/icons/bilibili.svg"`,
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("expected the markup to contain %s, got:\n%s", want, rendered)
+		}
+	}
+}
+
+func TestBilibiliVideosOnPartial(t *testing.T) {
+	posted := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		mode string
+		// how many of the requests to feed b fail after the first update
+		failures   int
+		want       []string
+		wantNotice bool
+	}{
+		{
+			mode:       "show",
+			failures:   2,
+			want:       []string{"https://www.bilibili.com/video/A2", "https://www.bilibili.com/video/A1"},
+			wantNotice: true,
+		},
+		{
+			mode:     "retry-once",
+			failures: 1,
+			want:     []string{"https://www.bilibili.com/video/A2", "https://www.bilibili.com/video/A1", "https://www.bilibili.com/video/B1"},
+		},
+		{
+			// the retry fails too, so it's the same as show
+			mode:       "retry-once",
+			failures:   2,
+			want:       []string{"https://www.bilibili.com/video/A2", "https://www.bilibili.com/video/A1"},
+			wantNotice: true,
+		},
+		{
+			mode:       "keep-previous",
+			failures:   2,
+			want:       []string{"https://www.bilibili.com/video/A1", "https://www.bilibili.com/video/B1"},
+			wantNotice: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s with %d failures", test.mode, test.failures), func(t *testing.T) {
+			updated := false
+			failures := 0
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/a" && !updated:
+					w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("A1", "A1", posted))))
+				case r.URL.Path == "/a":
+					w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("A2", "A2", time.Now()), bilibiliTestItem("A1", "A1", posted))))
+				case updated && failures < test.failures:
+					failures++
+					w.WriteHeader(http.StatusInternalServerError)
+				default:
+					w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("B1", "B1", posted.Add(-time.Minute)))))
+				}
+			}))
+			defer server.Close()
+
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: ["+server.URL+"/a, "+server.URL+"/b]\non-partial: "+test.mode+"\n")
+
+			widget.update(context.Background())
+			if len(widget.Videos) != 2 || widget.Notice != nil {
+				t.Fatalf("expected the first update to fully succeed, got %v with notice %v", bilibiliVideoUrls(widget.Videos), widget.Notice)
+			}
+
+			updated = true
+			widget.update(context.Background())
+
+			if got := bilibiliVideoUrls(widget.Videos); !slices.Equal(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+
+			if (widget.Notice != nil) != test.wantNotice {
+				t.Errorf("expected a notice: %v, got %v", test.wantNotice, widget.Notice)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosImageProxyShards(t *testing.T) {
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls: [https://rsshub.example/bilibili/user/video/1]
+image-proxy: https://proxy.example/?url=
+image-proxy-shards: [img1, img2]
+`)
+	widget.ContentAvailable = true
+
+	videos := func() bilibiliVideoList {
+		videos := make(bilibiliVideoList, 20)
+		for i := range videos {
+			direct := fmt.Sprintf("https://i0.hdslb.com/%d.jpg", i)
+			videos[i] = bilibiliVideo{
+				Title:              fmt.Sprintf("Video %d", i),
+				Url:                fmt.Sprintf("https://www.bilibili.com/video/BV%d", i),
+				ThumbnailUrl:       widget.ImageProxy + direct,
+				DirectThumbnailUrl: direct,
+				TimePosted:         time.Now(),
+			}
+		}
+
+		videos.withShardedImageProxy(widget.ImageProxy, widget.shardedImageProxies)
+		return videos
+	}
+
+	widget.Videos = videos()
+	rendered := string(widget.Render())
+
+	for _, shard := range []string{"img1", "img2"} {
+		if !strings.Contains(rendered, `src="https://`+shard+`.proxy.example/?url=https://i0.hdslb.com/`) {
+			t.Errorf("expected some thumbnails to be loaded through %s, got:\n%s", shard, rendered)
+		}
+	}
+
+	if strings.Contains(rendered, `src="https://proxy.example/`) {
+		t.Error("expected every proxied thumbnail to be loaded through one of the shards")
+	}
+
+	// each thumbnail has to stay on the same shard so that it stays cached
+	again := videos()
+	for i := range again {
+		if again[i].ThumbnailUrl != widget.Videos[i].ThumbnailUrl {
+			t.Errorf("expected %s to stay on the same shard, got %s", widget.Videos[i].ThumbnailUrl, again[i].ThumbnailUrl)
+		}
+	}
+}
+
+func TestBilibiliVideosExtensionFields(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": `{"items": [{
+			"url": "https://www.bilibili.com/video/BV1",
+			"title": "Video",
+			"date_published": "2024-05-10T12:00:00Z",
+			"_bilibili": {"play_count": 12345, "tname": "Gaming", "stats": {"likes": 10}, "tags": ["a", "b"]}
+		}]}`,
+	})
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls: [`+server.URL+`/feed]
+extension-fields: [_bilibili.tname, _bilibili.play_count, _bilibili.tags, _bilibili.missing]
+`)
+	widget.update(context.Background())
+
+	if len(widget.Videos) != 1 {
+		t.Fatalf("expected 1 video, got %d", len(widget.Videos))
+	}
+
+	video := widget.Videos[0]
+
+	if video.Extra["_bilibili.tname"] != "Gaming" || video.Extra["_bilibili.play_count"] != float64(12345) {
+		t.Errorf("expected the configured fields to be captured, got %v", video.Extra)
+	}
+
+	if _, exists := video.Extra["_bilibili.missing"]; exists {
+		t.Error("expected fields missing from the item not to be captured")
+	}
+
+	// arrays and objects are captured but can't be shown as labels
+	if want := []string{"Gaming", "12345"}; !slices.Equal(video.ExtraLabels, want) {
+		t.Errorf("expected the labels %v, got %v", want, video.ExtraLabels)
+	}
+
+	rendered := string(widget.Render())
+	for _, want := range []string{`<li class="shrink-0">Gaming</li>`, `<li class="shrink-0">12345</li>`} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("expected the markup to contain %s, got:\n%s", want, rendered)
+		}
+	}
+}
+
+func TestBilibiliVideosRetryOnEmpty(t *testing.T) {
+	defer func(delay time.Duration) { bilibiliRetryOnEmptyDelay = delay }(bilibiliRetryOnEmptyDelay)
+	bilibiliRetryOnEmptyDelay = time.Millisecond
+
+	tests := []struct {
+		name   string
+		config string
+		// whether another feed has videos, in which case an empty one is believed
+		withOther    bool
+		wantRequests int
+		want         []string
+	}{
+		{name: "disabled", wantRequests: 1},
+		{name: "enabled", config: "retry-on-empty: true\n", wantRequests: 2, want: []string{"https://www.bilibili.com/video/BV1"}},
+		{name: "other feed has videos", config: "retry-on-empty: true\n", withOther: true, wantRequests: 1, want: []string{"https://www.bilibili.com/video/BV2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/other" {
+					w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("BV2", "Other", time.Now().Add(-time.Hour)))))
+					return
+				}
+
+				mu.Lock()
+				requests++
+				first := requests == 1
+				mu.Unlock()
+
+				if first {
+					w.Write([]byte(bilibiliTestFeed()))
+					return
+				}
+
+				w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("BV1", "Flaky", time.Now()))))
+			}))
+			defer server.Close()
+
+			feeds := server.URL + "/flaky"
+			if test.withOther {
+				feeds += ", " + server.URL + "/other"
+			}
+
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: ["+feeds+"]\n"+test.config)
+			widget.update(context.Background())
+
+			if requests != test.wantRequests {
+				t.Errorf("expected the flaky feed to be requested %d times, got %d", test.wantRequests, requests)
+			}
+
+			if got := bilibiliVideoUrls(widget.Videos); !slices.Equal(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosMinItems(t *testing.T) {
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls: [https://rsshub.example/bilibili/user/video/1]
+min-items: 2
+`)
+
+	widget.ContentAvailable = true
+	widget.Videos = bilibiliVideoList{{
+		Title:      "First video",
+		Url:        "https://www.bilibili.com/video/BV1",
+		TimePosted: time.Now().Add(-time.Hour),
+	}}
+
+	if rendered := widget.Render(); rendered != "" {
+		t.Fatalf("expected the widget to be hidden with fewer videos than min-items, got:\n%s", rendered)
+	}
+
+	widget.Videos = append(widget.Videos, bilibiliVideo{
+		Title:      "Second video",
+		Url:        "https://www.bilibili.com/video/BV2",
+		TimePosted: time.Now().Add(-2 * time.Hour),
+	})
+
+	if rendered := string(widget.Render()); !strings.Contains(rendered, "First video") || !strings.Contains(rendered, "Second video") {
+		t.Fatalf("expected the videos to be shown once there are min-items of them, got:\n%s", rendered)
+	}
+
+	// errors are still shown instead of hiding the widget
+	widget.ContentAvailable = false
+	widget.Videos = nil
+	widget.Error = errNoContent
+	if widget.Render() == "" {
+		t.Fatal("expected the widget to be shown when it has no content")
+	}
+}
+
+func TestBilibiliVideosFetchesHigherPriorityFeedsFirst(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+
+		w.Write([]byte(bilibiliTestFeed()))
+	}))
+	t.Cleanup(server.Close)
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls:
+  - `+server.URL+`/low
+  - url: `+server.URL+`/high
+    priority: 10
+  - url: `+server.URL+`/medium
+    priority: 5
+  - `+server.URL+`/also-low
+`)
+	// a single worker fetches the feeds in the order they get dispatched in
+	widget.concurrencyLimit = &adaptiveConcurrencyLimit{limit: 1}
+	widget.update(context.Background())
+
+	if want := []string{"/high", "/medium", "/low", "/also-low"}; !slices.Equal(requested, want) {
+		t.Errorf("expected the feeds to be fetched in the order %v, got %v", want, requested)
+	}
+}
+
+func TestBilibiliVideosCollapseAnimation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name:   "grid by default",
+			config: "style: grid-cards\n",
+			want:   `data-collapse-animation-duration="200"`,
+		},
+		{
+			name:   "list with a custom duration",
+			config: "style: vertical-list\ncollapse-animation-duration: 350\n",
+			want:   `data-collapse-animation-duration="350"`,
+		},
+		{
+			name:   "grid with the animation disabled",
+			config: "style: grid-cards\ncollapse-animation: false\ncollapse-animation-duration: 350\n",
+		},
+		{
+			name:   "list with the animation disabled",
+			config: "style: vertical-list\ncollapse-animation: false\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\n"+test.config)
+
+			widget.ContentAvailable = true
+			widget.Videos = bilibiliVideoList{{
+				Title:      "Video",
+				Url:        "https://www.bilibili.com/video/BV1",
+				TimePosted: time.Now(),
+			}}
+
+			rendered := string(widget.Render())
+
+			if test.want == "" {
+				if strings.Contains(rendered, "data-collapse-animation-duration") {
+					t.Errorf("expected the markup not to contain an animation duration, got:\n%s", rendered)
+				}
+			} else if !strings.Contains(rendered, test.want) {
+				t.Errorf("expected the markup to contain %s, got:\n%s", test.want, rendered)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosAuthorAliases(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": `{"items": [
+			{"url": "https://www.bilibili.com/video/BV1", "title": "Aliased", "date_published": "2024-05-10T12:00:00Z", "authors": [{"name": "RawName_Official_2024"}]},
+			{"url": "https://www.bilibili.com/video/BV2", "title": "Unmapped", "date_published": "2024-05-10T11:00:00Z", "authors": [{"name": "Someone Else"}]}
+		]}`,
+	})
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls: [`+server.URL+`/feed]
+author-aliases:
+  RawName_Official_2024: Friendly Name
+`)
+	widget.update(context.Background())
+
+	if len(widget.Videos) != 2 {
+		t.Fatalf("expected 2 videos, got %d", len(widget.Videos))
+	}
+
+	rendered := string(widget.Render())
+
+	for _, want := range []string{"Friendly Name", "Someone Else"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("expected the markup to contain %q, got:\n%s", want, rendered)
+		}
+	}
+
+	if strings.Contains(rendered, "RawName_Official_2024") {
+		t.Errorf("expected the raw author name not to be shown, got:\n%s", rendered)
+	}
+}
+
+func TestBilibiliVideoListCapPerAuthorPerDay(t *testing.T) {
+	day := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	video := func(id string, author string, posted time.Time) bilibiliVideo {
+		return bilibiliVideo{Url: "https://www.bilibili.com/video/" + id, Author: author, TimePosted: posted}
+	}
+
+	videos := bilibiliVideoList{
+		video("A1", "Prolific", day.Add(20*time.Hour)),
+		video("A2", "Prolific", day.Add(18*time.Hour)),
+		video("W1", "Weekly", day.Add(17*time.Hour)),
+		video("A3", "Prolific", day.Add(15*time.Hour)),
+		video("A4", "Prolific", day.Add(10*time.Hour)),
+		video("A5", "Prolific", day.Add(-time.Hour)),
+		video("A6", "Prolific", day.Add(-2*time.Hour)),
+	}
+
+	tests := []struct {
+		name     string
+		location *time.Location
+		want     []string
+	}{
+		{
+			name:     "utc",
+			location: time.UTC,
+			want:     []string{"A1", "A2", "W1", "A5", "A6"},
+		},
+		{
+			// the first two are posted the next day in UTC+8, and the last
+			// two on the same day as the ones in between
+			name:     "widget timezone",
+			location: time.FixedZone("UTC+8", 8*60*60),
+			want:     []string{"A1", "A2", "W1", "A3", "A4"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			capped := videos.capPerAuthorPerDay(2, test.location)
+
+			want := make([]string, len(test.want))
+			for i := range test.want {
+				want[i] = "https://www.bilibili.com/video/" + test.want[i]
+			}
+
+			if got := bilibiliVideoUrls(capped); !slices.Equal(got, want) {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosRetryAfterTotalFailure(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/ok": bilibiliTestFeed(bilibiliTestItem("BV1", "Video", time.Now())),
+	})
+
+	tests := []struct {
+		name        string
+		config      string
+		feeds       []string
+		wantShorter bool
+	}{
+		{
+			name:        "total failure",
+			config:      "retry-after-total-failure: 30s\n",
+			feeds:       []string{"/missing", "/also-missing"},
+			wantShorter: true,
+		},
+		{
+			name:  "total failure without the option",
+			feeds: []string{"/missing", "/also-missing"},
+		},
+		{
+			// only some feeds failing isn't a sign of an outage
+			name:   "partial failure",
+			config: "retry-after-total-failure: 30s\n",
+			feeds:  []string{"/ok", "/missing"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := "cache: 1h\nrsshuburls:\n"
+			for _, feed := range test.feeds {
+				config += "  - " + server.URL + feed + "\n"
+			}
+
+			widget := newTestBilibiliVideosWidget(t, config+test.config)
+
+			// the early retries after repeated failures grow past the option's duration
+			for range 3 {
+				widget.update(context.Background())
+
+				shortened := time.Until(widget.nextUpdate) <= 30*time.Second
+				if shortened != test.wantShorter {
+					t.Fatalf("expected the next update to be shortened: %t, got it in %v", test.wantShorter, time.Until(widget.nextUpdate))
+				}
+			}
+		})
+	}
+}
+
+func TestBilibiliAppUrl(t *testing.T) {
+	tests := []struct {
+		videoUrl string
+		want     string
+	}{
+		{"https://www.bilibili.com/video/BV1xx411c7mD", "bilibili://video/BV1xx411c7mD"},
+		{"https://www.bilibili.com/video/BV1xx411c7mD/?p=2&t=30", "bilibili://video/BV1xx411c7mD"},
+		{"https://b23.tv/BV1xx411c7mD", "bilibili://video/BV1xx411c7mD"},
+		{"https://www.bilibili.com/video/av170001", ""},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", ""},
+	}
+
+	for _, test := range tests {
+		if got := bilibiliAppUrl(test.videoUrl); got != test.want {
+			t.Errorf("expected %q for %s, got %q", test.want, test.videoUrl, got)
+		}
+	}
+}
+
+func TestBilibiliVideosAppDeepLinks(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": bilibiliTestFeed(bilibiliTestItem("BV1xx411c7mD", "Video", time.Now())),
+	})
+
+	for _, enabled := range []bool{true, false} {
+		widget := newTestBilibiliVideosWidget(t, fmt.Sprintf("rsshuburls: [%s/feed]\napp-deep-links: %t\n", server.URL, enabled))
+		widget.update(context.Background())
+
+		rendered := string(widget.Render())
+		if !strings.Contains(rendered, `href="https://www.bilibili.com/video/BV1xx411c7mD"`) {
+			t.Errorf("expected the web URL to stay the link's href, got:\n%s", rendered)
+		}
+
+		hasAppLink := strings.Contains(rendered, `data-app-href="bilibili://video/BV1xx411c7mD"`)
+		if hasAppLink != enabled {
+			t.Errorf("expected the app link to be rendered: %t, got:\n%s", enabled, rendered)
+		}
+	}
+}
+
+func TestBilibiliVideosPlatformIcon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><link rel="icon" href="/static/site.png"></head></html>`))
+	}))
+	defer server.Close()
+
+	setCachedFavicon("unknown-without-favicon.example", "")
+
+	tests := []struct {
+		mode     string
+		want     []string
+		unwanted []string
+	}{
+		{
+			mode: "favicon",
+			want: []string{
+				`<img class="video-source-icon flat-icon" src="/static/icons/bilibili.svg"`,
+				`<img class="video-source-icon" src="` + server.URL + `/static/site.png"`,
+				`<img class="video-source-icon flat-icon" src="/static/icons/link.svg"`,
+			},
+		},
+		{
+			// unknown hosts get the generic icon without looking up their favicon
+			mode: "platform",
+			want: []string{
+				`<img class="video-source-icon flat-icon" src="/static/icons/bilibili.svg"`,
+				`<img class="video-source-icon flat-icon" src="/static/icons/link.svg"`,
+			},
+			unwanted: []string{"site.png"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\nplatform-icon: "+test.mode+"\n")
+			widget.ContentAvailable = true
+			widget.Videos = bilibiliVideoList{
+				{Title: "Known", Url: "https://www.bilibili.com/video/BV1", TimePosted: time.Now()},
+				{Title: "Unknown", Url: server.URL + "/video/2", TimePosted: time.Now()},
+				{Title: "Unknown without favicon", Url: "https://unknown-without-favicon.example/video/3", TimePosted: time.Now()},
+			}
+			widget.Videos.withPlatformIcons(widget.PlatformIcon == "favicon", widget.Providers.assetResolver)
+
+			rendered := string(widget.Render())
+
+			for _, want := range test.want {
+				if !strings.Contains(rendered, want) {
+					t.Errorf("expected the markup to contain %s, got:\n%s", want, rendered)
+				}
+			}
+
+			for _, unwanted := range test.unwanted {
+				if strings.Contains(rendered, unwanted) {
+					t.Errorf("expected the markup not to contain %s, got:\n%s", unwanted, rendered)
+				}
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosHoverPreview(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": `{"items": [
+			{"url": "https://www.bilibili.com/video/BV1", "title": "With preview", "content_html": "<img src=\"https://i0.hdslb.com/1.jpg\">", "preview": "https://i0.hdslb.com/1.gif", "date_published": "2024-05-10T12:00:00Z"},
+			{"url": "https://www.bilibili.com/video/BV2", "title": "Without preview", "content_html": "<img src=\"https://i0.hdslb.com/2.jpg\">", "date_published": "2024-05-10T11:00:00Z"}
+		]}`,
+	})
+
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "disabled"},
+		// previews go through the image proxy like thumbnails do
+		{name: "enabled", config: "hover-preview: true\n", want: "//wsrv.nl/?url=https://i0.hdslb.com/1.gif"},
+		{name: "proxied", config: "hover-preview: true\nimage-proxy: https://proxy.example/\n", want: "https://proxy.example/https://i0.hdslb.com/1.gif"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: ["+server.URL+"/feed]\n"+test.config)
+			widget.update(context.Background())
+
+			if len(widget.Videos) != 2 {
+				t.Fatalf("expected 2 videos, got %d", len(widget.Videos))
+			}
+
+			if widget.Videos[0].PreviewUrl != test.want || widget.Videos[1].PreviewUrl != "" {
+				t.Errorf("expected the preview URLs %q and none, got %q and %q", test.want, widget.Videos[0].PreviewUrl, widget.Videos[1].PreviewUrl)
+			}
+
+			rendered := string(widget.Render())
+
+			if count := strings.Count(rendered, "data-preview-src="); count != min(len(test.want), 1) {
+				t.Errorf("expected a preview on a single thumbnail at most, got %d in:\n%s", count, rendered)
+			}
+
+			if test.want != "" && !strings.Contains(rendered, `data-preview-src="`+test.want+`"`) {
+				t.Errorf("expected the markup to contain the preview URL %s, got:\n%s", test.want, rendered)
+			}
+		})
+	}
+}
+
+var bilibiliCardPattern = regexp.MustCompile(`<div class="card ([^"]*)" data-index="([^"]*)"`)
+
+func TestBilibiliVideosCardPositions(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		// the positions of the cards, which start over in each carousel
+		want []string
+	}{
+		{name: "horizontal cards", want: []string{"0", "1", "2"}},
+		{name: "grid cards", config: "style: grid-cards\n", want: []string{"0", "1", "2"}},
+		{name: "author carousel", config: "group: author-carousel\n", want: []string{"0", "1", "0"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\n"+test.config)
+			widget.ContentAvailable = true
+			widget.Videos = bilibiliVideoList{
+				{Title: "A1", Url: "https://www.bilibili.com/video/BV1", Author: "A", TimePosted: time.Now()},
+				{Title: "A2", Url: "https://www.bilibili.com/video/BV2", Author: "A", TimePosted: time.Now().Add(-time.Minute)},
+				{Title: "B1", Url: "https://www.bilibili.com/video/BV3", Author: "B", TimePosted: time.Now().Add(-2 * time.Minute)},
+			}
+			widget.Groups = widget.Videos.groupByAuthor()
+
+			cards := bilibiliCardPattern.FindAllStringSubmatch(string(widget.Render()), -1)
+
+			positions := make([]string, len(cards))
+			for i, card := range cards {
+				positions[i] = card[2]
+
+				isFirst := slices.Contains(strings.Fields(card[1]), "is-first")
+				if isFirst != (card[2] == "0") {
+					t.Errorf("card %d: expected is-first only on the first card, got classes %q", i, card[1])
+				}
+			}
+
+			if !slices.Equal(positions, test.want) {
+				t.Errorf("expected the positions %v, got %v", test.want, positions)
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosRequestTimeoutWithinDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+
+		w.Write([]byte(bilibiliTestFeed(bilibiliTestItem("BV"+strings.TrimPrefix(r.URL.Path, "/"), "Video", time.Now()))))
+	}))
+	defer server.Close()
+
+	widget := newTestBilibiliVideosWidget(t, "rsshuburls: ["+server.URL+"/slow, "+server.URL+"/fast]\ndeadline: 10s\n")
+	// set directly since durations in the config can't go below a second
+	widget.RequestTimeout = durationField(100 * time.Millisecond)
+
+	startedAt := time.Now()
+	widget.update(context.Background())
+
+	if elapsed := time.Since(startedAt); elapsed > 2*time.Second {
+		t.Errorf("expected the slow feed to time out on its own, the update took %v", elapsed)
+	}
+
+	if want := []string{"https://www.bilibili.com/video/BVfast"}; !slices.Equal(bilibiliVideoUrls(widget.Videos), want) {
+		t.Errorf("expected the videos %v, got %v", want, bilibiliVideoUrls(widget.Videos))
+	}
+
+	if !errors.Is(widget.Notice, errPartialContent) {
+		t.Errorf("expected the slow feed to have failed, got the notice %v", widget.Notice)
+	}
+}
+
+var authorColorPattern = regexp.MustCompile(`--author-color: ([^;]*);`)
+
+func TestBilibiliVideosAuthorColors(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": `{"items": [
+			{"url": "https://www.bilibili.com/video/BV1", "title": "A1", "date_published": "2024-05-10T12:00:00Z", "authors": [{"name": "Alice"}]},
+			{"url": "https://www.bilibili.com/video/BV2", "title": "B1", "date_published": "2024-05-10T11:00:00Z", "authors": [{"name": "Bob"}]},
+			{"url": "https://www.bilibili.com/video/BV3", "title": "A2", "date_published": "2024-05-10T10:00:00Z", "authors": [{"name": "Alice"}]},
+			{"url": "https://www.bilibili.com/video/BV4", "title": "C1", "date_published": "2024-05-10T09:00:00Z", "authors": [{"name": "Carol"}]}
+		]}`,
+	})
+
+	renderColors := func(config string) []string {
+		t.Helper()
+
+		widget := newTestBilibiliVideosWidget(t, "rsshuburls: ["+server.URL+"/feed]\n"+config)
+		widget.update(context.Background())
+
+		var colors []string
+		for _, match := range authorColorPattern.FindAllStringSubmatch(string(widget.Render()), -1) {
+			colors = append(colors, match[1])
+		}
+
+		if len(colors) != 4 {
+			t.Fatalf("expected a color for each of the 4 cards, got %v", colors)
+		}
+
+		return colors
+	}
+
+	first := renderColors("author-colors: auto\n")
+
+	if first[0] != first[2] {
+		t.Errorf("expected the videos of the same author to have the same color, got %s and %s", first[0], first[2])
+	}
+
+	if first[0] == first[1] || first[1] == first[3] {
+		t.Errorf("expected different authors to have different colors, got %v", first)
+	}
+
+	// a separate widget, as after a restart
+	if second := renderColors("author-colors: auto\n"); !slices.Equal(first, second) {
+		t.Errorf("expected the same colors across renders, got %v and %v", first, second)
+	}
+
+	overridden := renderColors("author-colors:\n  Bob: 200 50 50\n")
+	if overridden[1] != "hsl(200, 50%, 50%)" {
+		t.Errorf("expected the configured color for Bob, got %s", overridden[1])
+	}
+
+	if overridden[0] != first[0] || overridden[3] != first[3] {
+		t.Errorf("expected the other authors to still be colored automatically, got %v", overridden)
+	}
+}
+
+func TestBilibiliVideosSquareListThumbnails(t *testing.T) {
+	for _, square := range []bool{true, false} {
+		config := "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\nstyle: vertical-list\n"
+		if square {
+			config += "list-thumbnail: square\n"
+		}
+
+		widget := newTestBilibiliVideosWidget(t, config)
+		widget.ContentAvailable = true
+		widget.Videos = bilibiliVideoList{{
+			Title:        "Video",
+			Url:          "https://www.bilibili.com/video/BV1",
+			ThumbnailUrl: "https://i0.hdslb.com/1.jpg",
+			TimePosted:   time.Now(),
+		}}
+
+		rendered := string(widget.Render())
+		if regexp.MustCompile(`<div class="widget-content[^"]*\blist-thumbnails-square\b`).MatchString(rendered) != square {
+			t.Errorf("expected the content to have the square thumbnails class: %t, got:\n%s", square, rendered)
+		}
+	}
+
+	// the cards have room for the thumbnails as they are
+	widget := &bilibiliVideosWidget{}
+	if err := yaml.Unmarshal([]byte("type: bilibili-videos\nrsshuburls: [https://rsshub.example/bilibili/user/video/1]\nstyle: grid-cards\nlist-thumbnail: square\n"), widget); err != nil {
+		t.Fatalf("unmarshaling config: %v", err)
+	}
+
+	if err := widget.initialize(); err == nil || !strings.Contains(err.Error(), "only supported with the vertical-list style") {
+		t.Errorf("expected square thumbnails to be rejected for cards, got %v", err)
+	}
+}
+
+func TestBilibiliVideosFeatureAuthor(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": `{"items": [
+			{"url": "https://www.bilibili.com/video/BVb1", "title": "Bob newest", "date_published": "2024-05-10T12:00:00Z", "authors": [{"name": "Bob"}]},
+			{"url": "https://www.bilibili.com/video/BVa1", "title": "Alice newest", "date_published": "2024-05-10T11:00:00Z", "authors": [{"name": "Alice"}]},
+			{"url": "https://www.bilibili.com/video/BVb2", "title": "Bob older", "date_published": "2024-05-10T10:00:00Z", "authors": [{"name": "Bob"}]},
+			{"url": "https://www.bilibili.com/video/BVa2", "title": "Alice older", "date_published": "2024-05-10T09:00:00Z", "authors": [{"name": "Alice"}]}
+		]}`,
+	})
+
+	for _, style := range []string{"", "grid-cards"} {
+		t.Run("style "+style, func(t *testing.T) {
+			// the author is matched regardless of case
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: ["+server.URL+"/feed]\nfeature-author: alice\nstyle: "+style+"\n")
+			widget.update(context.Background())
+
+			cards := strings.Split(string(widget.Render()), `<div class="card `)[1:]
+			if len(cards) != 4 {
+				t.Fatalf("expected 4 cards, got %d", len(cards))
+			}
+
+			if !strings.HasPrefix(cards[0], "widget-content-frame thumbnail-parent featured-video") || !strings.Contains(cards[0], "Alice newest") {
+				t.Errorf("expected the newest video of Alice to be the featured card, got:\n%s", cards[0])
+			}
+
+			for i, want := range []string{"Bob newest", "Bob older", "Alice older"} {
+				card := cards[i+1]
+
+				if strings.Contains(card, "featured-video") || !strings.Contains(card, want) {
+					t.Errorf("expected card %d to be a regular card for %s, got:\n%s", i+1, want, card)
+				}
+
+				if strings.Contains(card, "Alice newest") {
+					t.Errorf("expected the featured video not to be repeated in card %d", i+1)
+				}
+			}
+		})
+	}
+}
+
+var collapseKeyPattern = regexp.MustCompile(`class="[^"]*\bcollapsible-container\b[^"]*"[^>]*\bdata-collapse-key="([^"]*)"`)
+
+func TestBilibiliVideosCollapseKey(t *testing.T) {
+	now := time.Now()
+	videos := bilibiliVideoList{
+		{Title: "First", Url: "https://www.bilibili.com/video/BV1", Author: "Alice", TimePosted: now},
+		{Title: "Second", Url: "https://www.bilibili.com/video/BV2", Author: "Bob", TimePosted: now.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		group  bool
+		want   []string
+	}{
+		{name: "grid", config: "style: grid-cards\n", want: []string{"42"}},
+		{name: "list", config: "style: vertical-list\n", want: []string{"42"}},
+		// each group collapses on its own and so needs a key of its own
+		{name: "list in sections", config: "style: vertical-list\nlayout: sections\n", group: true, want: []string{"42-Alice", "42-Bob"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			widget := newTestBilibiliVideosWidget(t, "rsshuburls: [https://rsshub.example/bilibili/user/video/1]\n"+test.config)
+			widget.setID(42)
+			widget.ContentAvailable = true
+			widget.Videos = slices.Clone(videos)
+			if test.group {
+				widget.Groups = widget.Videos.groupByAuthor()
+			}
+
+			// the key has to stay the same for the position to be restored
+			// after the content gets replaced
+			for range 2 {
+				var keys []string
+				for _, match := range collapseKeyPattern.FindAllStringSubmatch(string(widget.Render()), -1) {
+					keys = append(keys, match[1])
+				}
+
+				if !slices.Equal(keys, test.want) {
+					t.Errorf("expected the keys %v, got %v", test.want, keys)
+				}
+			}
+		})
+	}
+}
+
+func TestBilibiliVideosDetectGaps(t *testing.T) {
+	var mu sync.Mutex
+	var ids []int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		items := make([]string, len(ids))
+		for i, id := range ids {
+			items[i] = fmt.Sprintf(`{"id": "item-%d", "url": "https://www.bilibili.com/video/BV%d", "title": "Video %d", "date_published": %q}`,
+				id, id, id, time.Now().Add(-time.Duration(i)*time.Minute).Format(time.RFC3339))
+		}
+
+		w.Write([]byte(bilibiliTestFeed(items...)))
+	}))
+	defer server.Close()
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls:
+  - url: `+server.URL+`/critical
+    detect-gaps: true
+  - `+server.URL+`/other
+`)
+
+	steps := []struct {
+		ids     []int
+		wantGap string
+	}{
+		// nothing to compare against yet
+		{ids: []int{100, 99, 98}},
+		{ids: []int{105, 104, 101, 100}, wantGap: "possible gap in " + server.URL + "/critical: 2 items between #100 and #105 were never seen"},
+		{ids: []int{107, 106, 105}},
+	}
+
+	for i, step := range steps {
+		mu.Lock()
+		ids = step.ids
+		mu.Unlock()
+
+		widget.update(context.Background())
+
+		notice := ""
+		if widget.Notice != nil {
+			notice = widget.Notice.Error()
+		}
+
+		if step.wantGap == "" && notice != "" {
+			t.Errorf("step %d: expected no notice, got %q", i, notice)
+		}
+
+		if step.wantGap != "" && (!strings.Contains(notice, step.wantGap) || strings.Contains(notice, "/other")) {
+			t.Errorf("step %d: expected the notice %q, got %q", i, step.wantGap, notice)
+		}
+	}
+}
+
+func TestBilibiliVideosSourceTimezone(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/local": `{"items": [
+			{"url": "https://www.bilibili.com/video/BV1", "title": "Without offset", "date_published": "2024-05-10T20:00:00"},
+			{"url": "https://www.bilibili.com/video/BV2", "title": "With offset", "date_published": "2024-05-10T19:00:00Z"}
+		]}`,
+		"/utc": `{"items": [
+			{"url": "https://www.bilibili.com/video/BV3", "title": "Elsewhere", "date_published": "2024-05-10T18:00:00"}
+		]}`,
+	})
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls:
+  - url: `+server.URL+`/local
+    source-timezone: Asia/Shanghai
+  - `+server.URL+`/utc
+`)
+	widget.update(context.Background())
+
+	want := map[string]time.Time{
+		// 20:00 in UTC+8
+		"https://www.bilibili.com/video/BV1": time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC),
+		"https://www.bilibili.com/video/BV2": time.Date(2024, 5, 10, 19, 0, 0, 0, time.UTC),
+		// feeds without a source timezone keep treating dates as UTC
+		"https://www.bilibili.com/video/BV3": time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC),
+	}
+
+	if len(widget.Videos) != len(want) {
+		t.Fatalf("expected %d videos, got %d", len(want), len(widget.Videos))
+	}
+
+	for _, video := range widget.Videos {
+		if !video.TimePosted.Equal(want[video.Url]) {
+			t.Errorf("expected %s to have been posted at %v, got %v", video.Url, want[video.Url], video.TimePosted.UTC())
+		}
+	}
+}
+
+func TestBilibiliVideosSkipBacklog(t *testing.T) {
+	now := time.Now()
+	feeds := map[string]string{
+		"/feed": bilibiliTestFeed(
+			bilibiliTestItem("BV2", "Newest", now.Add(-1*time.Hour)),
+			bilibiliTestItem("BV1", "Backlog", now.Add(-48*time.Hour)),
+		),
+	}
+	server := newTestBilibiliFeedServer(t, feeds)
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls: [`+server.URL+`/feed]
+skip-backlog: true
+`)
+
+	widget.update(context.Background())
+	if got, want := bilibiliVideoUrls(widget.Videos), []string{"https://www.bilibili.com/video/BV2"}; !slices.Equal(got, want) {
+		t.Fatalf("expected the first fetch to only show %v, got %v", want, got)
+	}
+
+	feeds["/feed"] = bilibiliTestFeed(
+		bilibiliTestItem("BV3", "Posted later", now.Add(-10*time.Minute)),
+		bilibiliTestItem("BV2", "Newest", now.Add(-1*time.Hour)),
+		bilibiliTestItem("BV1", "Backlog", now.Add(-48*time.Hour)),
+	)
+
+	widget.update(context.Background())
+	want := []string{"https://www.bilibili.com/video/BV3", "https://www.bilibili.com/video/BV2"}
+	if got := bilibiliVideoUrls(widget.Videos); !slices.Equal(got, want) {
+		t.Errorf("expected videos posted after the first fetch to show up as %v, got %v", want, got)
+	}
+}
+
+func TestBilibiliVideosPublishedWithinPerFeed(t *testing.T) {
+	now := time.Now()
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/news": bilibiliTestFeed(
+			bilibiliTestItem("BV1", "Recent news", now.Add(-2*time.Hour)),
+			bilibiliTestItem("BV2", "Old news", now.Add(-20*time.Hour)),
+		),
+		"/tutorials": bilibiliTestFeed(
+			bilibiliTestItem("BV3", "Recent tutorial", now.Add(-10*24*time.Hour)),
+			bilibiliTestItem("BV4", "Old tutorial", now.Add(-40*24*time.Hour)),
+		),
+	})
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls:
+  - url: `+server.URL+`/news
+    published-within: 12h
+  - url: `+server.URL+`/tutorials
+    published-within: 30d
+`)
+	widget.update(context.Background())
+
+	want := []string{
+		"https://www.bilibili.com/video/BV1",
+		"https://www.bilibili.com/video/BV3",
+	}
+
+	if got := bilibiliVideoUrls(widget.Videos); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBilibiliVideosDisabledFeeds(t *testing.T) {
+	var mu sync.Mutex
+	requested := make(map[string]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.URL.Path]++
+		mu.Unlock()
+
+		id := "BV" + strings.Trim(r.URL.Path, "/")
+		w.Write([]byte(bilibiliTestFeed(bilibiliTestItem(id, "Video", time.Now()))))
+	}))
+	t.Cleanup(server.Close)
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls:
+  - `+server.URL+`/1
+  - url: `+server.URL+`/2
+    disabled: true
+  - url: `+server.URL+`/3
+    disabled: false
+`)
+	widget.update(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if requested["/2"] != 0 {
+		t.Errorf("expected the disabled feed to not be requested, got %d requests", requested["/2"])
+	}
+
+	for _, path := range []string{"/1", "/3"} {
+		if requested[path] != 1 {
+			t.Errorf("expected the enabled feed %s to be requested once, got %d requests", path, requested[path])
+		}
+	}
+
+	want := []string{"https://www.bilibili.com/video/BV1", "https://www.bilibili.com/video/BV3"}
+	if got := bilibiliVideoUrls(widget.Videos); !slices.Equal(slices.Sorted(slices.Values(got)), want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBilibiliVideosBreakpointLimits(t *testing.T) {
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls: [https://rsshub.example/bilibili/user/video/1]
+style: grid-cards
+breakpoint-limits:
+  mobile: 4
+  desktop: 12
+`)
+	widget.ContentAvailable = true
+	widget.Videos = bilibiliVideoList{
+		{Title: "Video", Url: "https://www.bilibili.com/video/BV1", TimePosted: time.Now()},
+	}
+
+	rendered := string(widget.Render())
+
+	for _, attr := range []string{`data-limit-mobile="4"`, `data-limit-desktop="12"`} {
+		if !strings.Contains(rendered, attr) {
+			t.Errorf("expected the grid to have %s", attr)
+		}
+	}
+
+	// breakpoints without a limit fall back to collapse-after-rows
+	if strings.Contains(rendered, "data-limit-tablet") {
+		t.Error("expected the grid to not have a tablet limit")
+	}
+
+	negative := &bilibiliVideosWidget{}
+	if err := yaml.Unmarshal([]byte("type: bilibili-videos\nbreakpoint-limits:\n  tablet: -1\n"), negative); err != nil {
+		t.Fatalf("unmarshaling config: %v", err)
+	}
+
+	if err := negative.initialize(); err == nil {
+		t.Error("expected negative limits to be rejected")
+	}
+}
+
+func TestBilibiliVideosThumbnailSrcset(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": bilibiliTestFeed(bilibiliTestItem("BV1", "Video", time.Now())),
+	})
+
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls: [`+server.URL+`/feed]
+image-proxy: https://proxy.example/?url=
+thumbnail-widths: [320, 640, 960]
+`)
+	widget.update(context.Background())
+
+	rendered := string(widget.Render())
+
+	thumbnail := "https://proxy.example/?url=https://i0.hdslb.com/BV1.jpg"
+	srcset := fmt.Sprintf(`srcset="%[1]s&amp;w=320 320w, %[1]s&amp;w=640 640w, %[1]s&amp;w=960 960w"`, thumbnail)
+	if !strings.Contains(rendered, srcset) {
+		t.Errorf("expected the thumbnail to have %s", srcset)
+	}
+
+	if sizes := `sizes="(max-width: 550px) 100vw, 320px"`; !strings.Contains(rendered, sizes) {
+		t.Errorf("expected the thumbnail to have %s", sizes)
+	}
+
+	if src := `src="` + thumbnail + `"`; !strings.Contains(rendered, src) {
+		t.Errorf("expected the thumbnail to keep %s for browsers without srcset support", src)
+	}
+}
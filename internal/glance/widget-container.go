@@ -12,7 +12,7 @@ type containerWidgetBase struct {
 
 func (widget *containerWidgetBase) _initializeWidgets() error {
 	for i := range widget.Widgets {
-		if err := widget.Widgets[i].initialize(); err != nil {
+		if err := initializeWidget(widget.Widgets[i]); err != nil {
 			return formatWidgetInitError(err, widget.Widgets[i])
 		}
 	}
@@ -34,19 +34,43 @@ func (widget *containerWidgetBase) _update(ctx context.Context) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			widget.update(ctx)
+			updateWidget(ctx, widget)
 		}()
 	}
 
 	wg.Wait()
 }
 
+func (widget *containerWidgetBase) childWidgets() widgets {
+	return widget.Widgets
+}
+
 func (widget *containerWidgetBase) _setProviders(providers *widgetProviders) {
 	for i := range widget.Widgets {
 		widget.Widgets[i].setProviders(providers)
 	}
 }
 
+func (widget *containerWidgetBase) preloadImages() []preloadImage {
+	var images []preloadImage
+
+	for i := range widget.Widgets {
+		if preloading, ok := widget.Widgets[i].(imagePreloadingWidget); ok {
+			images = append(images, preloading.preloadImages()...)
+		}
+	}
+
+	return images
+}
+
+func (widget *containerWidgetBase) setPageSeenSet(seen map[string]struct{}) {
+	for i := range widget.Widgets {
+		if deduping, ok := widget.Widgets[i].(pageDedupingWidget); ok {
+			deduping.setPageSeenSet(seen)
+		}
+	}
+}
+
 func (widget *containerWidgetBase) _requiresUpdate(now *time.Time) bool {
 	for i := range widget.Widgets {
 		if widget.Widgets[i].requiresUpdate(now) {
@@ -39,7 +39,7 @@ func (widget *customAPIWidget) initialize() error {
 		return errors.New("template is required")
 	}
 
-	compiledTemplate, err := template.New("").Funcs(customAPITemplateFuncs).Parse(widget.Template)
+	compiledTemplate, err := template.New("").Funcs(customAPITemplateFuncs).Parse(withNoncePlaceholders(widget.Template))
 	if err != nil {
 		return fmt.Errorf("parsing template: %w", err)
 	}
@@ -11,6 +11,7 @@ type htmlWidget struct {
 
 func (widget *htmlWidget) initialize() error {
 	widget.withTitle("").withError(nil)
+	widget.Source = template.HTML(withNoncePlaceholders(string(widget.Source)))
 
 	return nil
 }
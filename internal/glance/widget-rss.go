@@ -36,7 +36,9 @@ type rssWidget struct {
 	CollapseAfter    int              `yaml:"collapse-after"`
 	SingleLineTitles bool             `yaml:"single-line-titles"`
 	PreserveOrder    bool             `yaml:"preserve-order"`
-	NoItemsMessage   string           `yaml:"-"`
+	// when set, descriptions get cut at a word boundary instead of mid-word
+	MaxDescriptionLength int    `yaml:"max-description-length"`
+	NoItemsMessage       string `yaml:"-"`
 }
 
 func (widget *rssWidget) initialize() error {
@@ -58,9 +60,14 @@ func (widget *rssWidget) initialize() error {
 		widget.CardHeight = 0
 	}
 
+	if widget.MaxDescriptionLength < 0 {
+		widget.MaxDescriptionLength = 0
+	}
+
 	if widget.Style == "detailed-list" {
 		for i := range widget.FeedRequests {
 			widget.FeedRequests[i].IsDetailed = true
+			widget.FeedRequests[i].MaxDescriptionLength = widget.MaxDescriptionLength
 		}
 	}
 
@@ -111,7 +118,9 @@ type rssFeedItem struct {
 	ImageURL    string
 	Categories  []string
 	Description string
-	PublishedAt time.Time
+	// the untruncated description, only set when Description was truncated
+	FullDescription string
+	PublishedAt     time.Time
 }
 
 // doesn't cover all cases but works the vast majority of the time
@@ -152,6 +161,8 @@ type rssFeedRequest struct {
 	ItemLinkPrefix  string            `yaml:"item-link-prefix"`
 	Headers         map[string]string `yaml:"headers"`
 	IsDetailed      bool              `yaml:"-"`
+
+	MaxDescriptionLength int `yaml:"-"`
 }
 
 type rssFeedItemList []rssFeedItem
@@ -240,7 +251,18 @@ func fetchItemsFromRSSFeedTask(request rssFeedRequest) ([]rssFeedItem, error) {
 
 		if request.IsDetailed {
 			if !request.HideDescription && item.Description != "" && item.Title != "" {
-				rssItem.Description = shortenFeedDescriptionLen(item.Description, 200)
+				if request.MaxDescriptionLength > 0 {
+					description, _ := limitStringLength(item.Description, 5000)
+					description = sanitizeFeedDescription(description)
+
+					var truncated bool
+					rssItem.Description, truncated = truncateAtWordBoundary(description, request.MaxDescriptionLength)
+					if truncated {
+						rssItem.FullDescription = description
+					}
+				} else {
+					rssItem.Description = shortenFeedDescriptionLen(item.Description, 200)
+				}
 			}
 
 			if !request.HideCategories {
@@ -0,0 +1,150 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var shlinkWidgetTemplate = mustParseTemplate("shlink.html", "widget-base.html")
+
+type shlinkWidget struct {
+	widgetBase    `yaml:",inline"`
+	ShortURLs     shlinkShortURLList `yaml:"-"`
+	InstanceURL   string             `yaml:"instance-url"`
+	APIKey        string             `yaml:"api-key"`
+	Limit         int                `yaml:"limit"`
+	CollapseAfter int                `yaml:"collapse-after"`
+}
+
+func (widget *shlinkWidget) initialize() error {
+	widget.withTitle("Short Links").withCacheDuration(1 * time.Hour)
+
+	if widget.InstanceURL == "" {
+		return errors.New("instance-url is required")
+	}
+
+	if widget.APIKey == "" {
+		return errors.New("api-key is required")
+	}
+
+	widget.InstanceURL = strings.TrimRight(widget.InstanceURL, "/")
+
+	if widget.Limit <= 0 {
+		widget.Limit = 10
+	}
+
+	if widget.CollapseAfter == 0 || widget.CollapseAfter < -1 {
+		widget.CollapseAfter = 5
+	}
+
+	return nil
+}
+
+func (widget *shlinkWidget) update(ctx context.Context) {
+	shortURLs, err := fetchShlinkShortURLs(widget.InstanceURL, widget.APIKey, widget.Limit)
+
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	widget.ShortURLs = shortURLs
+}
+
+func (widget *shlinkWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, shlinkWidgetTemplate)
+}
+
+type shlinkShortURL struct {
+	ShortCode string
+	ShortURL  string
+	LongURL   string
+	Title     string
+	Visits    int
+}
+
+type shlinkShortURLList []shlinkShortURL
+
+func (s shlinkShortURLList) sortByVisits() shlinkShortURLList {
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Visits > s[j].Visits
+	})
+
+	return s
+}
+
+type shlinkShortURLsResponseJson struct {
+	ShortURLs struct {
+		Data []struct {
+			ShortCode     string `json:"shortCode"`
+			ShortURL      string `json:"shortUrl"`
+			LongURL       string `json:"longUrl"`
+			Title         string `json:"title"`
+			VisitsSummary *struct {
+				Total int `json:"total"`
+			} `json:"visitsSummary"`
+			// deprecated in newer versions of Shlink in favor of visitsSummary
+			VisitsCount int `json:"visitsCount"`
+		} `json:"data"`
+	} `json:"shortUrls"`
+}
+
+func fetchShlinkShortURLs(instanceURL, apiKey string, limit int) (shlinkShortURLList, error) {
+	query := url.Values{}
+	query.Set("orderBy", "visits-DESC")
+	query.Set("itemsPerPage", strconv.Itoa(limit))
+
+	request, err := http.NewRequest("GET", instanceURL+"/rest/v3/short-urls?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("X-Api-Key", apiKey)
+	request.Header.Set("Accept", "application/json")
+
+	task := decodeJsonFromRequestTask[shlinkShortURLsResponseJson](defaultHTTPClient)
+	job := newJob(task, []*http.Request{request})
+	responses, errs, err := workerPoolDo(job)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not fetch short URLs: %v", errNoContent, err)
+	}
+
+	if errs[0] != nil {
+		return nil, fmt.Errorf("%w: could not fetch short URLs: %v", errNoContent, errs[0])
+	}
+
+	response := responses[0]
+
+	shortURLs := make(shlinkShortURLList, 0, len(response.ShortURLs.Data))
+
+	for _, data := range response.ShortURLs.Data {
+		shortURL := shlinkShortURL{
+			ShortCode: data.ShortCode,
+			ShortURL:  data.ShortURL,
+			LongURL:   data.LongURL,
+			Title:     data.Title,
+			Visits:    data.VisitsCount,
+		}
+
+		if data.VisitsSummary != nil {
+			shortURL.Visits = data.VisitsSummary.Total
+		}
+
+		shortURLs = append(shortURLs, shortURL)
+	}
+
+	shortURLs.sortByVisits()
+
+	if len(shortURLs) > limit {
+		shortURLs = shortURLs[:limit]
+	}
+
+	return shortURLs, nil
+}
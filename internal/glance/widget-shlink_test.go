@@ -0,0 +1,104 @@
+package glance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const shlinkShortURLsSampleResponse = `{
+  "shortUrls": {
+    "data": [
+      {
+        "shortCode": "docs",
+        "shortUrl": "https://s.test/docs",
+        "longUrl": "https://example.com/docs",
+        "title": "Docs",
+        "visitsSummary": {"total": 12, "nonBots": 10, "bots": 2}
+      },
+      {
+        "shortCode": "blog",
+        "shortUrl": "https://s.test/blog",
+        "longUrl": "https://example.com/blog",
+        "title": null,
+        "visitsSummary": {"total": 40, "nonBots": 40, "bots": 0}
+      },
+      {
+        "shortCode": "old",
+        "shortUrl": "https://s.test/old",
+        "longUrl": "https://example.com/old",
+        "visitsCount": 25
+      }
+    ],
+    "pagination": {"currentPage": 1, "pagesCount": 1, "itemsPerPage": 10, "itemsInCurrentPage": 3, "totalItems": 3}
+  }
+}`
+
+func TestFetchShlinkShortURLs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/v3/short-urls" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+
+		if r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("expected the API key to be sent, got %q", r.Header.Get("X-Api-Key"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(shlinkShortURLsSampleResponse))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []shlinkShortURL
+	}{
+		{
+			name:  "sorted by visits",
+			limit: 10,
+			want: []shlinkShortURL{
+				{ShortCode: "blog", ShortURL: "https://s.test/blog", LongURL: "https://example.com/blog", Visits: 40},
+				{ShortCode: "old", ShortURL: "https://s.test/old", LongURL: "https://example.com/old", Visits: 25},
+				{ShortCode: "docs", ShortURL: "https://s.test/docs", LongURL: "https://example.com/docs", Title: "Docs", Visits: 12},
+			},
+		},
+		{
+			name:  "limited",
+			limit: 1,
+			want: []shlinkShortURL{
+				{ShortCode: "blog", ShortURL: "https://s.test/blog", LongURL: "https://example.com/blog", Visits: 40},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shortURLs, err := fetchShlinkShortURLs(server.URL, "secret", test.limit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(shortURLs) != len(test.want) {
+				t.Fatalf("expected %d short URLs, got %d: %+v", len(test.want), len(shortURLs), shortURLs)
+			}
+
+			for i := range test.want {
+				if shortURLs[i] != test.want[i] {
+					t.Errorf("short URL %d: expected %+v, got %+v", i, test.want[i], shortURLs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFetchShlinkShortURLsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	if _, err := fetchShlinkShortURLs(server.URL, "wrong", 10); err == nil {
+		t.Fatal("expected an error for an unauthorized response")
+	}
+}
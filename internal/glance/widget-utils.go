@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math/rand/v2"
 	"net/http"
 	"strconv"
@@ -17,8 +18,9 @@ import (
 )
 
 var (
-	errNoContent      = errors.New("failed to retrieve any content")
-	errPartialContent = errors.New("failed to retrieve some of the content")
+	errNoContent        = errors.New("failed to retrieve any content")
+	errPartialContent   = errors.New("failed to retrieve some of the content")
+	errTooManyRedirects = errors.New("too many redirects")
 )
 
 const defaultClientTimeout = 5 * time.Second
@@ -34,6 +36,65 @@ var defaultInsecureHTTPClient = &http.Client{
 	},
 }
 
+var defaultHTTPClientsForceHTTP1 = false
+
+// Some servers misbehave when using HTTP/2, which results in stream errors
+// that look like random failures, so optionally make every request over HTTP/1.1
+func configureDefaultHTTPClients(forceHTTP1 bool) {
+	// only swap the transports when the option actually changed between config reloads
+	if forceHTTP1 == defaultHTTPClientsForceHTTP1 {
+		return
+	}
+
+	defaultHTTPClientsForceHTTP1 = forceHTTP1
+
+	if !forceHTTP1 {
+		defaultHTTPClient.Transport = nil
+		defaultInsecureHTTPClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+
+		return
+	}
+
+	defaultHTTPClient.Transport = newHTTP1Transport(nil)
+	defaultInsecureHTTPClient.Transport = newHTTP1Transport(&tls.Config{InsecureSkipVerify: true})
+}
+
+func newHTTP1Transport(tlsConfig *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.ForceAttemptHTTP2 = false
+	// a non-nil empty map is what disables HTTP/2
+	transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+
+	return transport
+}
+
+// Returns a copy of the client that gives up after following maxRedirects
+// redirects, which makes looping redirects show up as such rather than
+// as whatever error the last response happened to cause
+func withMaxRedirects(client *http.Client, maxRedirects int) *http.Client {
+	limited := *client
+	limited.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		chain := make([]string, 0, len(via)+1)
+		for _, previous := range via {
+			chain = append(chain, previous.URL.String())
+		}
+		chain = append(chain, request.URL.String())
+
+		slog.Debug("Following redirect", "chain", chain)
+
+		if len(via) > maxRedirects {
+			return fmt.Errorf("%w: gave up after %d", errTooManyRedirects, maxRedirects)
+		}
+
+		return nil
+	}
+
+	return &limited
+}
+
 type requestDoer interface {
 	Do(*http.Request) (*http.Response, error)
 }
@@ -140,6 +201,7 @@ type workerPoolJob[I any, O any] struct {
 	workers int
 	task    func(I) (O, error)
 	ctx     context.Context
+	tracker *concurrencyTracker
 }
 
 const defaultNumWorkers = 10
@@ -164,6 +226,11 @@ func (job *workerPoolJob[I, O]) withWorkers(workers int) *workerPoolJob[I, O] {
 // 	return job
 // }
 
+func (job *workerPoolJob[I, O]) withConcurrencyTracker(tracker *concurrencyTracker) *workerPoolJob[I, O] {
+	job.tracker = tracker
+	return job
+}
+
 func newJob[I any, O any](task func(I) (O, error), data []I) *workerPoolJob[I, O] {
 	return &workerPoolJob[I, O]{
 		workers: defaultNumWorkers,
@@ -192,7 +259,16 @@ func workerPoolDo[I any, O any](job *workerPoolJob[I, O]) ([]O, []error, error)
 			defer wg.Done()
 
 			for t := range tasksQueue {
+				if job.tracker != nil {
+					job.tracker.begin()
+				}
+
 				t.output, t.err = job.task(t.input)
+
+				if job.tracker != nil {
+					job.tracker.end()
+				}
+
 				resultsQueue <- t
 			}
 		}()
@@ -0,0 +1,98 @@
+package glance
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestConfigureDefaultHTTPClientsForceHTTP1(t *testing.T) {
+	t.Cleanup(func() { configureDefaultHTTPClients(false) })
+
+	configureDefaultHTTPClients(true)
+
+	for name, client := range map[string]*http.Client{
+		"default":  defaultHTTPClient,
+		"insecure": defaultInsecureHTTPClient,
+	} {
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("expected the %s client to have its own transport, got %T", name, client.Transport)
+		}
+
+		if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil || len(transport.TLSNextProto) != 0 {
+			t.Errorf("expected the transport of the %s client to be limited to HTTP/1.1", name)
+		}
+	}
+
+	if !defaultInsecureHTTPClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify {
+		t.Error("expected the insecure client to still skip verifying certificates")
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	response, err := defaultInsecureHTTPClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+
+	if response.ProtoMajor != 1 {
+		t.Errorf("expected the request to be made over HTTP/1.1, got %s", response.Proto)
+	}
+
+	configureDefaultHTTPClients(false)
+
+	if defaultHTTPClient.Transport != nil {
+		t.Error("expected the default client to go back to the default transport")
+	}
+}
+
+func TestWithMaxRedirects(t *testing.T) {
+	// /redirect/N redirects N more times before landing on the page
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remaining, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/redirect/"))
+		if err != nil {
+			w.Write([]byte("landed"))
+			return
+		}
+
+		if remaining == 0 {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/redirect/%d", remaining-1), http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := withMaxRedirects(server.Client(), 3)
+
+	response, err := client.Get(server.URL + "/redirect/2")
+	if err != nil {
+		t.Fatalf("expected 3 redirects to be followed, got %v", err)
+	}
+	response.Body.Close()
+
+	_, err = client.Get(server.URL + "/redirect/3")
+	if !errors.Is(err, errTooManyRedirects) {
+		t.Fatalf("expected a too many redirects error, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "gave up after 3") {
+		t.Errorf("expected the error to mention the limit, got %v", err)
+	}
+
+	if server.Client().CheckRedirect != nil {
+		t.Error("expected the original client to be left as is")
+	}
+}
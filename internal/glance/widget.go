@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"math"
 	"net/http"
+	"runtime/debug"
 	"sync/atomic"
 	"time"
 
@@ -59,6 +60,8 @@ func newWidget(widgetType string) (widget, error) {
 		w = &lobstersWidget{}
 	case "change-detection":
 		w = &changeDetectionWidget{}
+	case "shlink":
+		w = &shlinkWidget{}
 	case "repository":
 		w = &repositoryWidget{}
 	case "search":
@@ -126,12 +129,26 @@ type widget interface {
 	GetID() uint64
 
 	initialize() error
+	validateLocale() error
 	requiresUpdate(*time.Time) bool
 	setProviders(*widgetProviders)
 	update(context.Context)
+	handleUpdatePanic(any)
 	setID(uint64)
 	handleRequest(w http.ResponseWriter, r *http.Request)
 	setHideHeader(bool)
+	getMetrics() widgetMetricsSnapshot
+	getConfigID() string
+}
+
+// Checks what's shared by every widget before letting the widget itself
+// check and set up the rest of its config
+func initializeWidget(w widget) error {
+	if err := w.validateLocale(); err != nil {
+		return err
+	}
+
+	return w.initialize()
 }
 
 type cacheType int
@@ -143,27 +160,41 @@ const (
 )
 
 type widgetBase struct {
-	ID                  uint64           `yaml:"-"`
-	Providers           *widgetProviders `yaml:"-"`
-	Type                string           `yaml:"type"`
-	Title               string           `yaml:"title"`
-	TitleURL            string           `yaml:"title-url"`
-	CSSClass            string           `yaml:"css-class"`
-	CustomCacheDuration durationField    `yaml:"cache"`
-	ContentAvailable    bool             `yaml:"-"`
-	WIP                 bool             `yaml:"-"`
-	Error               error            `yaml:"-"`
-	Notice              error            `yaml:"-"`
-	templateBuffer      bytes.Buffer     `yaml:"-"`
-	cacheDuration       time.Duration    `yaml:"-"`
-	cacheType           cacheType        `yaml:"-"`
-	nextUpdate          time.Time        `yaml:"-"`
-	updateRetriedTimes  int              `yaml:"-"`
-	HideHeader          bool             `yaml:"-"`
+	ID uint64 `yaml:"-"`
+	// assigned in the config, unlike ID it doesn't change as widgets get
+	// added or moved around
+	ConfigID                 string               `yaml:"id"`
+	Providers                *widgetProviders     `yaml:"-"`
+	Type                     string               `yaml:"type"`
+	Title                    string               `yaml:"title"`
+	TitleURL                 string               `yaml:"title-url"`
+	CSSClass                 string               `yaml:"css-class"`
+	CustomCacheDuration      durationField        `yaml:"cache"`
+	QuietHours               quietHoursField      `yaml:"quiet-hours"`
+	Locale                   string               `yaml:"locale"`
+	Messages                 map[string]string    `yaml:"messages"`
+	ShowCountdown            bool                 `yaml:"show-countdown"`
+	FailureWebhook           string               `yaml:"failure-webhook"`
+	FailureWebhookAfter      int                  `yaml:"failure-webhook-after"`
+	CircuitBreaker           *circuitBreakerField `yaml:"circuit-breaker"`
+	ContentAvailable         bool                 `yaml:"-"`
+	WIP                      bool                 `yaml:"-"`
+	Error                    error                `yaml:"-"`
+	Notice                   error                `yaml:"-"`
+	templateBuffer           bytes.Buffer         `yaml:"-"`
+	cacheDuration            time.Duration        `yaml:"-"`
+	cacheType                cacheType            `yaml:"-"`
+	nextUpdate               time.Time            `yaml:"-"`
+	updateRetriedTimes       int                  `yaml:"-"`
+	consecutiveFailures      int                  `yaml:"-"`
+	circuitBreakerMultiplier int                  `yaml:"-"`
+	HideHeader               bool                 `yaml:"-"`
+	metrics                  widgetMetrics        `yaml:"-"`
 }
 
 type widgetProviders struct {
-	assetResolver func(string) string
+	assetResolver      func(string) string
+	refreshMergeWindow time.Duration
 }
 
 func (w *widgetBase) requiresUpdate(now *time.Time) bool {
@@ -175,9 +206,24 @@ func (w *widgetBase) requiresUpdate(now *time.Time) bool {
 		return true
 	}
 
+	// keep serving the last known data until quiet hours are over
+	if w.ContentAvailable && w.QuietHours.contains(*now) {
+		return false
+	}
+
 	return now.After(w.nextUpdate)
 }
 
+// Returns when the widget will next fetch new data, or the zero time for
+// widgets that never do so after the first time
+func (w *widgetBase) NextUpdate() time.Time {
+	if w.cacheType == cacheTypeInfinite {
+		return time.Time{}
+	}
+
+	return w.nextUpdate
+}
+
 func (w *widgetBase) IsWIP() bool {
 	return w.WIP
 }
@@ -194,6 +240,10 @@ func (w *widgetBase) setID(id uint64) {
 	w.ID = id
 }
 
+func (w *widgetBase) getConfigID() string {
+	return w.ConfigID
+}
+
 func (w *widgetBase) setHideHeader(value bool) {
 	w.HideHeader = value
 }
@@ -286,6 +336,42 @@ func (w *widgetBase) withError(err error) *widgetBase {
 	return w
 }
 
+var errUpdatePanicked = errors.New("panicked while updating")
+
+// A panic while fetching or parsing shouldn't take down the entire server, so
+// it gets recovered and the widget keeps showing whatever it had before
+func updateWidget(ctx context.Context, widget widget) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			slog.Error(
+				"Recovered from panic while updating widget",
+				"type", widget.GetType(),
+				"id", widget.GetID(),
+				"panic", recovered,
+				"stack", string(debug.Stack()),
+			)
+
+			widget.handleUpdatePanic(recovered)
+		}
+	}()
+
+	widget.update(ctx)
+}
+
+func (w *widgetBase) handleUpdatePanic(recovered any) {
+	err := fmt.Errorf("%w: %v", errUpdatePanicked, recovered)
+
+	w.scheduleEarlyUpdate()
+	w.recordUpdateOutcome(err)
+
+	if w.ContentAvailable {
+		w.withNotice(err)
+		return
+	}
+
+	w.withError(err)
+}
+
 func (w *widgetBase) canContinueUpdateAfterHandlingErr(err error) bool {
 	// TODO: needs covering more edge cases.
 	// if there's partial content and we update early there's a chance
@@ -304,16 +390,19 @@ func (w *widgetBase) canContinueUpdateAfterHandlingErr(err error) bool {
 		w.scheduleEarlyUpdate()
 
 		if !errors.Is(err, errPartialContent) {
+			w.recordUpdateOutcome(err)
 			w.withError(err)
 			w.withNotice(nil)
 			return false
 		}
 
+		w.recordUpdateOutcome(nil)
 		w.withError(nil)
 		w.withNotice(err)
 		return true
 	}
 
+	w.recordUpdateOutcome(nil)
 	w.withNotice(nil)
 	w.withError(nil)
 	w.scheduleNextUpdate()
@@ -324,7 +413,18 @@ func (w *widgetBase) getNextUpdateTime() time.Time {
 	now := time.Now()
 
 	if w.cacheType == cacheTypeDuration {
-		return now.Add(w.cacheDuration)
+		next := now.Add(w.cacheDuration)
+
+		// snapping to a grid makes widgets whose updates would otherwise
+		// drift apart by a little update together in a single page load
+		if w.Providers != nil && w.Providers.refreshMergeWindow > 0 {
+			window := w.Providers.refreshMergeWindow
+			if snapped := next.Truncate(window); snapped.Before(next) {
+				next = snapped.Add(window)
+			}
+		}
+
+		return next
 	}
 
 	if w.cacheType == cacheTypeOnTheHour {
@@ -343,6 +443,16 @@ func (w *widgetBase) scheduleNextUpdate() *widgetBase {
 	return w
 }
 
+// Brings the next update forward if it's scheduled further away than the
+// given duration, the retry backoff of early updates can otherwise grow past it
+func (w *widgetBase) scheduleUpdateWithin(duration time.Duration) *widgetBase {
+	if latest := time.Now().Add(duration); latest.Before(w.nextUpdate) {
+		w.nextUpdate = latest
+	}
+
+	return w
+}
+
 func (w *widgetBase) scheduleEarlyUpdate() *widgetBase {
 	w.updateRetriedTimes++
 
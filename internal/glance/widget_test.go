@@ -0,0 +1,146 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequiresUpdateDuringQuietHours(t *testing.T) {
+	widget := newTestBilibiliVideosWidget(t, `
+rsshuburls: [https://rsshub.example/bilibili/user/video/1]
+quiet-hours:
+  start: "23:00"
+  end: "07:00"
+  timezone: UTC
+`)
+
+	night := time.Date(2024, 5, 10, 2, 0, 0, 0, time.UTC)
+	morning := time.Date(2024, 5, 10, 7, 30, 0, 0, time.UTC)
+
+	// widgets that haven't fetched anything yet still do so
+	widget.nextUpdate = night.Add(-time.Hour)
+	if !widget.requiresUpdate(&night) {
+		t.Error("expected a widget without content to update during quiet hours")
+	}
+
+	widget.ContentAvailable = true
+
+	if widget.requiresUpdate(&night) {
+		t.Error("expected the scheduled update to be skipped during quiet hours")
+	}
+
+	if !widget.requiresUpdate(&morning) {
+		t.Error("expected the skipped update to happen once quiet hours are over")
+	}
+}
+
+func TestGetNextUpdateTimeRefreshMergeWindow(t *testing.T) {
+	providers := &widgetProviders{refreshMergeWindow: time.Minute}
+
+	// cache durations that would have the widgets update 30 seconds apart,
+	// both ending within the minute before the tick
+	now := time.Now()
+	tick := now.Add(time.Hour).Truncate(time.Minute).Add(time.Minute)
+
+	first := &widgetBase{
+		cacheType:     cacheTypeDuration,
+		cacheDuration: tick.Sub(now) - 40*time.Second,
+		Providers:     providers,
+	}
+	second := &widgetBase{
+		cacheType:     cacheTypeDuration,
+		cacheDuration: tick.Sub(now) - 10*time.Second,
+		Providers:     providers,
+	}
+
+	if got := first.getNextUpdateTime(); !got.Equal(tick) {
+		t.Errorf("expected the first widget to update at %v, got %v", tick, got)
+	}
+
+	if got := second.getNextUpdateTime(); !got.Equal(tick) {
+		t.Errorf("expected the second widget to update at %v, got %v", tick, got)
+	}
+
+	unaligned := &widgetBase{
+		cacheType:     cacheTypeDuration,
+		cacheDuration: tick.Sub(now) - 40*time.Second,
+		Providers:     &widgetProviders{},
+	}
+
+	if got := unaligned.getNextUpdateTime(); !got.Before(tick.Add(-30 * time.Second)) {
+		t.Errorf("expected updates not to be aligned without a merge window, got %v", got)
+	}
+}
+
+func TestShowCountdown(t *testing.T) {
+	server := newTestBilibiliFeedServer(t, map[string]string{
+		"/feed": bilibiliTestFeed(bilibiliTestItem("BV1", "Video", time.Now())),
+	})
+
+	for _, enabled := range []bool{true, false} {
+		widget := newTestBilibiliVideosWidget(t, fmt.Sprintf("rsshuburls: [%s/feed]\ncache: 1h\nshow-countdown: %t\n", server.URL, enabled))
+		widget.update(context.Background())
+
+		rendered := string(widget.Render())
+		want := fmt.Sprintf(`data-next-refresh="%d"`, widget.nextUpdate.Unix())
+
+		if strings.Contains(rendered, want) != enabled {
+			t.Errorf("expected the markup to contain %s: %t, got:\n%s", want, enabled, rendered)
+		}
+	}
+
+	if !(&widgetBase{cacheType: cacheTypeInfinite, nextUpdate: time.Now()}).NextUpdate().IsZero() {
+		t.Error("expected widgets that never refresh not to have a next update")
+	}
+}
+
+func TestUpdateWidgetRecoversFromPanics(t *testing.T) {
+	const feedUrl = "https://rsshub.example/bilibili/user/video/1"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"feeds": [{"url": %q, "items": %s}]}`, feedUrl, bilibiliTestFeedItems(2))
+	}))
+	defer server.Close()
+
+	// the aggregator gets requested from the goroutine doing the update
+	panickingClient := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		panic("index out of range")
+	})}
+
+	widget := newTestBilibiliVideosWidget(t, "cache: 1h\naggregator: "+server.URL+"\nrsshuburls: ["+feedUrl+"]\n")
+
+	updateWidget(context.Background(), widget)
+	if !widget.ContentAvailable || len(widget.Videos) != 2 {
+		t.Fatalf("expected the first update to succeed, got %d videos and %v", len(widget.Videos), widget.Error)
+	}
+
+	widget.client = panickingClient
+	updateWidget(context.Background(), widget)
+
+	if !widget.ContentAvailable || len(widget.Videos) != 2 {
+		t.Errorf("expected the previous videos to be kept, got %d", len(widget.Videos))
+	}
+
+	if !errors.Is(widget.Notice, errUpdatePanicked) || widget.Error != nil {
+		t.Errorf("expected the panic to be shown as a notice, got the notice %v and the error %v", widget.Notice, widget.Error)
+	}
+
+	if time.Until(widget.nextUpdate) > 5*time.Minute {
+		t.Errorf("expected an early update to be scheduled, got one in %v", time.Until(widget.nextUpdate))
+	}
+
+	// without anything to fall back to, the panic is shown as an error
+	empty := newTestBilibiliVideosWidget(t, "aggregator: "+server.URL+"\nrsshuburls: ["+feedUrl+"]\n")
+	empty.client = panickingClient
+	updateWidget(context.Background(), empty)
+
+	if empty.ContentAvailable || !errors.Is(empty.Error, errUpdatePanicked) {
+		t.Errorf("expected the panic to be shown as an error, got %v", empty.Error)
+	}
+}